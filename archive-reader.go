@@ -0,0 +1,276 @@
+package pcommon
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+/*
+	ArchiveReader parses a CSV archive with its ArchiveDataTree:
+	  - the first line is a header if one of its fields is the title of a branch of the tree
+	  - the column of each branch is found by its OriginColumnTitle in the header, or at its OriginColumnIndex
+	  - the value of each branch is passed through its DataFilter, an empty result skipping the branch for the line
+	  - the data of the assets are grouped by bucket of the minimum timeframe (see Env.MIN_TIME_FRAME)
+
+	Lines must be sorted by time. An invalid line is skipped and reported (see Errors) without stopping the reading.
+*/
+
+// ArchiveLineError is the error of a line of an archive, the line being skipped.
+type ArchiveLineError struct {
+	// line number in the file, starting at 1
+	Line int
+	Err  error
+}
+
+func (e ArchiveLineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// ArchiveBucket holds the data read during a minimum timeframe, one value per line for each asset of the archive.
+type ArchiveBucket struct {
+	// start of the bucket
+	Time TimeUnit
+	Data map[AssetAddress]DataList
+}
+
+// Aggregate returns the candle of each asset of the bucket.
+func (b ArchiveBucket) Aggregate() map[AssetAddress]Data {
+	ret := make(map[AssetAddress]Data, len(b.Data))
+	for address, list := range b.Data {
+		ret[address] = list.Aggregate(Env.MIN_TIME_FRAME, b.Time)
+	}
+	return ret
+}
+
+type archiveReaderBranch struct {
+	branch   AssetBranch
+	address  AssetAddress
+	dataType DataType
+	// index of the column in the lines, resolved once the first line is read
+	index int
+}
+
+type ArchiveReader struct {
+	csv      *csv.Reader
+	time     archiveReaderBranch
+	branches []archiveReaderBranch
+
+	// nil if the archive has no header
+	header map[string]int
+	// true once the first line is read
+	started bool
+	// number of the last line read
+	line int
+	// bucket being filled, nil before the first valid line
+	pending *ArchiveBucket
+	errors  []ArchiveLineError
+	done    bool
+}
+
+// NewArchiveReader returns a reader of the assets of the tree for the set.
+// To read assets of a parametric branch not listed in the tree (see ArchiveDataTree.Branch), add them to a copy of the tree.
+func NewArchiveReader(r io.Reader, tree *ArchiveDataTree, setID []string) (*ArchiveReader, error) {
+	reader := &ArchiveReader{
+		csv:  csv.NewReader(r),
+		time: archiveReaderBranch{branch: tree.Time},
+	}
+
+	for _, branch := range tree.Columns {
+		config, ok := GetAssetConfig(branch.Asset)
+		if !ok {
+			return nil, fmt.Errorf("asset type %s is not registered", branch.Asset)
+		}
+		if config.DataType != UNIT && config.DataType != QUANTITY {
+			return nil, fmt.Errorf("asset type %s cannot be read from an archive", branch.Asset)
+		}
+		reader.branches = append(reader.branches, archiveReaderBranch{
+			branch:   branch,
+			address:  branch.Address(setID),
+			dataType: config.DataType,
+		})
+	}
+	return reader, nil
+}
+
+// Errors returns the errors of the lines skipped so far.
+func (r *ArchiveReader) Errors() []ArchiveLineError {
+	return r.errors
+}
+
+// Next returns the next complete bucket, or io.EOF once the archive is read.
+// The other errors are the ones of the underlying reader.
+func (r *ArchiveReader) Next() (*ArchiveBucket, error) {
+	for !r.done {
+		record, err := r.csv.Read()
+		if err == io.EOF {
+			r.done = true
+			break
+		}
+
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			r.line = parseErr.StartLine
+			r.addError(parseErr.Err)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		r.line, _ = r.csv.FieldPos(0)
+
+		if !r.started {
+			r.started = true
+			r.resolveColumns(record)
+			if r.header != nil {
+				continue
+			}
+		}
+
+		t, data, err := r.parseLine(record)
+		if err != nil {
+			r.addError(err)
+			continue
+		}
+
+		start := timeframeStart(t, Env.MIN_TIME_FRAME)
+		if r.pending != nil && start < r.pending.Time {
+			r.addError(fmt.Errorf("time %s is before the previous lines", t.Pretty()))
+			continue
+		}
+
+		var ret *ArchiveBucket
+		if r.pending != nil && start > r.pending.Time {
+			ret = r.pending
+			r.pending = nil
+		}
+		if r.pending == nil {
+			r.pending = &ArchiveBucket{Time: start, Data: map[AssetAddress]DataList{}}
+		}
+		for address, d := range data {
+			list, ok := r.pending.Data[address]
+			if !ok {
+				list = NewTypeTimeArray(d.Type())
+			}
+			r.pending.Data[address] = list.Append(d)
+		}
+		if ret != nil {
+			return ret, nil
+		}
+	}
+
+	if r.pending != nil {
+		ret := r.pending
+		r.pending = nil
+		return ret, nil
+	}
+	return nil, io.EOF
+}
+
+// ReadArchive reads the whole archive and returns its buckets along with the errors of the skipped lines.
+func ReadArchive(reader io.Reader, tree *ArchiveDataTree, setID []string) ([]ArchiveBucket, []ArchiveLineError, error) {
+	r, err := NewArchiveReader(reader, tree, setID)
+	if err != nil {
+		return nil, nil, err
+	}
+	ret := []ArchiveBucket{}
+	for {
+		bucket, err := r.Next()
+		if err == io.EOF {
+			return ret, r.Errors(), nil
+		}
+		if err != nil {
+			return nil, r.Errors(), err
+		}
+		ret = append(ret, *bucket)
+	}
+}
+
+func (r *ArchiveReader) addError(err error) {
+	r.errors = append(r.errors, ArchiveLineError{Line: r.line, Err: err})
+}
+
+// resolveColumns detects the header from the first line and sets the column index of each branch.
+func (r *ArchiveReader) resolveColumns(firstLine []string) {
+	titles := map[string]bool{normalizeColumnTitle(r.time.branch.OriginColumnTitle): true}
+	for _, b := range r.branches {
+		titles[normalizeColumnTitle(b.branch.OriginColumnTitle)] = true
+	}
+	for _, field := range firstLine {
+		if title := normalizeColumnTitle(field); title != "" && titles[title] {
+			r.header = make(map[string]int, len(firstLine))
+			break
+		}
+	}
+	if r.header != nil {
+		for i, field := range firstLine {
+			r.header[normalizeColumnTitle(field)] = i
+		}
+	}
+
+	resolve := func(b *archiveReaderBranch) {
+		b.index = b.branch.OriginColumnIndex
+		if idx, ok := r.header[normalizeColumnTitle(b.branch.OriginColumnTitle)]; ok {
+			b.index = idx
+		}
+	}
+	resolve(&r.time)
+	for i := range r.branches {
+		resolve(&r.branches[i])
+	}
+}
+
+func normalizeColumnTitle(title string) string {
+	return strings.ToLower(strings.TrimSpace(title))
+}
+
+// parseLine returns the time of the line and the data of each asset it contains.
+func (r *ArchiveReader) parseLine(line []string) (TimeUnit, map[AssetAddress]Data, error) {
+	rawTime, err := r.filter(r.time, line)
+	if err != nil {
+		return 0, nil, fmt.Errorf("time: %s", err)
+	}
+	timeInt, err := strconv.ParseInt(rawTime, 10, 64)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid time %s", rawTime)
+	}
+	t := NewTimeUnit(timeInt)
+
+	ret := make(map[AssetAddress]Data)
+	for _, b := range r.branches {
+		raw, err := r.filter(b, line)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%s: %s", b.branch.Asset, err)
+		}
+		if raw == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return 0, nil, fmt.Errorf("%s: invalid value %s", b.branch.Asset, raw)
+		}
+		ret[b.address] = NewTypeTime(b.dataType, v, t)
+	}
+	return t, ret, nil
+}
+
+// filter returns the value of the branch in the line, passed through its DataFilter.
+// A DataFilter panicking on the line (ex: reading a missing column) makes the line invalid.
+func (r *ArchiveReader) filter(b archiveReaderBranch, line []string) (ret string, err error) {
+	if b.index < 0 || b.index >= len(line) {
+		return "", fmt.Errorf("missing column %d", b.index)
+	}
+	data := strings.TrimSpace(line[b.index])
+	if b.branch.DataFilter == nil {
+		return data, nil
+	}
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			ret, err = "", fmt.Errorf("invalid line: %v", recovered)
+		}
+	}()
+	return b.branch.DataFilter(data, line, r.header)
+}
@@ -0,0 +1,115 @@
+package pcommon
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/samber/lo"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArchiveReaderTrades(t *testing.T) {
+	setID := []string{"btc", "usdt"}
+	price := AssetAddressParsed{SetID: setID, AssetType: Asset.FUTURES_PRICE}.BuildAddress()
+	volume := AssetAddressParsed{SetID: setID, AssetType: Asset.FUTURES_VOLUME}.BuildAddress()
+
+	// columns are found by title, whatever their order
+	withHeader := `is_buyer_maker,id,qty,price,quote_qty,time
+true,1,0.5,100,50,1587607200000
+false,2,0.25,102,25.5,1587607200400
+true,3,abc,101,0,1587607200800
+true,4,1,103,103,1587607201100
+5,2,104
+true,6,1,99,99,1587607200900
+false,7,3,105,315,1587607203000
+`
+	buckets, lineErrors, err := ReadArchive(strings.NewReader(withHeader), &BINANCE_FUTURES_TRADE_ARCHIVE_TREE, setID)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(buckets))
+	assert.Equal(t, []int{4, 6, 7}, lo.Map(lineErrors, func(e ArchiveLineError, _ int) int { return e.Line }), lineErrors)
+
+	assert.Equal(t, NewTimeUnit(1587607200000), buckets[0].Time)
+	assert.Equal(t, NewTimeUnit(1587607201000), buckets[1].Time)
+	assert.Equal(t, NewTimeUnit(1587607203000), buckets[2].Time)
+	assert.Equal(t, 2, buckets[0].Data[price].Len())
+	assert.Equal(t, 2, buckets[0].Data[volume].Len())
+
+	candles := buckets[0].Aggregate()
+	assert.Equal(t, 100.0, candles[price].(UnitTime).Open)
+	assert.Equal(t, 102.0, candles[price].(UnitTime).Close)
+	assert.Equal(t, 0.5, candles[volume].(QuantityTime).Plus)
+	assert.Equal(t, 0.25, candles[volume].(QuantityTime).Minus)
+
+	// without header, columns are found by index
+	withoutHeader := `1,100,0.5,50,1587607200000,true
+2,102,0.25,25.5,1587607200400,false
+`
+	buckets, lineErrors, err = ReadArchive(strings.NewReader(withoutHeader), &BINANCE_FUTURES_TRADE_ARCHIVE_TREE, setID)
+	assert.Nil(t, err)
+	assert.Empty(t, lineErrors)
+	assert.Equal(t, 1, len(buckets))
+	candles = buckets[0].Aggregate()
+	assert.Equal(t, 102.0, candles[price].(UnitTime).Close)
+	assert.Equal(t, 0.25, candles[volume].(QuantityTime).Minus)
+
+	// lines too short for the columns of the tree are reported
+	short := `1,100,0.5,50,1587607200000
+2,102,0.25,25.5,1587607200400
+`
+	buckets, lineErrors, err = ReadArchive(strings.NewReader(short), &BINANCE_FUTURES_TRADE_ARCHIVE_TREE, setID)
+	assert.Nil(t, err)
+	assert.Empty(t, buckets)
+	assert.Equal(t, []int{1, 2}, lo.Map(lineErrors, func(e ArchiveLineError, _ int) int { return e.Line }))
+
+	// a data filter panicking on a line does not stop the reading
+	tree := BINANCE_FUTURES_TRADE_ARCHIVE_TREE
+	tree.Columns = []AssetBranch{{
+		OriginColumnTitle: "price",
+		OriginColumnIndex: 1,
+		Asset:             Asset.FUTURES_PRICE,
+		DataFilter: func(data string, line []string, header map[string]int) (string, error) {
+			return line[9], nil
+		},
+	}}
+	buckets, lineErrors, err = ReadArchive(strings.NewReader(withoutHeader), &tree, setID)
+	assert.Nil(t, err)
+	assert.Empty(t, buckets)
+	assert.Equal(t, 2, len(lineErrors))
+}
+
+func TestArchiveReaderBookDepth(t *testing.T) {
+	setID := []string{"btc", "usdt"}
+	archive := `timestamp,percentage,depth,notional
+2023-01-01 00:00:08,-2.00,120.5,2000000
+2023-01-01 00:00:08,-1.00,60.25,1000000
+2023-01-01 00:00:08,0.20,10,160000
+2023-01-01 00:00:08,1.00,50,900000
+2023-01-01 00:00:38,-1.00,61,1010000
+2023-01-01 00:00:38,x,61,1010000
+`
+	tree := BINANCE_BOOK_DEPTH_ARCHIVE_TREE
+	parametric, err := tree.Branch(AssetAddressParsed{AssetType: Asset.BOOK_DEPTH, Arguments: []string{"0.2"}})
+	assert.Nil(t, err)
+	tree.Columns = append(append([]AssetBranch{}, tree.Columns...), parametric)
+
+	r, err := NewArchiveReader(strings.NewReader(archive), &tree, setID)
+	assert.Nil(t, err)
+	first, err := r.Next()
+	assert.Nil(t, err)
+	second, err := r.Next()
+	assert.Nil(t, err)
+	_, err = r.Next()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, 1, len(r.Errors()))
+	assert.Equal(t, 7, r.Errors()[0].Line)
+
+	m1 := AssetAddress("btc_usdt;bd-m1;[];")
+	assert.Equal(t, 4, len(first.Data))
+	assert.Equal(t, 60.25, first.Data[m1].(UnitTimeArray)[0].Close)
+	assert.Equal(t, 120.5, first.Data["btc_usdt;bd-m2;[];"].(UnitTimeArray)[0].Close)
+	assert.Equal(t, 10.0, first.Data["btc_usdt;book_depth;[];0.2"].(UnitTimeArray)[0].Close)
+	assert.Equal(t, 1, len(second.Data))
+	assert.Equal(t, 61.0, second.Data[m1].(UnitTimeArray)[0].Close)
+	assert.Equal(t, first.Time.Add(Env.MIN_TIME_FRAME*30), second.Time)
+}
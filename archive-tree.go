@@ -2,6 +2,7 @@ package pcommon
 
 import (
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 	"time"
@@ -9,54 +10,6 @@ import (
 	"github.com/araddon/dateparse"
 )
 
-var GetBookDepthAssetPercentage = func(asset AssetType) (int, error) {
-	if asset == Asset.BOOK_DEPTH_M1 || asset == Asset.BOOK_DEPTH_M2 || asset == Asset.BOOK_DEPTH_M3 || asset == Asset.BOOK_DEPTH_M4 || asset == Asset.BOOK_DEPTH_M5 ||
-		asset == Asset.BOOK_DEPTH_P1 || asset == Asset.BOOK_DEPTH_P2 || asset == Asset.BOOK_DEPTH_P3 || asset == Asset.BOOK_DEPTH_P4 || asset == Asset.BOOK_DEPTH_P5 {
-		lastChar := asset[len(asset)-1:]
-		percent, err := strconv.Atoi(string(lastChar))
-		if err != nil {
-			return 0, err
-		}
-
-		isPlus := strings.HasPrefix(string(asset), "bd-p")
-		isMinus := strings.HasPrefix(string(asset), "bd-m")
-		if isPlus {
-			return percent, nil
-		} else if isMinus {
-			return -percent, nil
-		}
-	}
-	return 0, errors.New("invalid asset")
-}
-
-func GenericBookDepthDataFilter(asset AssetType) func(data string, line []string, header map[string]int) (string, error) {
-
-	return func(data string, line []string, header map[string]int) (string, error) {
-		idx, ok := header["percentage"]
-		var percent int = 0
-		var err error = nil
-		if ok {
-			percent, err = strconv.Atoi(line[idx])
-		} else {
-			percent, err = strconv.Atoi(line[1])
-		}
-		if err != nil {
-			return "", err
-		}
-		if percent < -5 || percent > 5 || percent == 0 {
-			return "", errors.New("invalid percentage")
-		}
-		bdPercent, err := GetBookDepthAssetPercentage(asset)
-		if err != nil {
-			return "", err
-		}
-		if percent == bdPercent {
-			return data, nil
-		}
-		return "", nil
-	}
-}
-
 var GenericTimeDataFilter = func(data string, line []string, header map[string]int) (string, error) {
 	t, err := dateparse.ParseAny(data)
 	if err != nil {
@@ -66,10 +19,35 @@ var GenericTimeDataFilter = func(data string, line []string, header map[string]i
 	return NewTimeUnitFromTime(utcTime).String(), nil
 }
 
+// GenericTradeQuantityDataFilter signs the quantity of a trade of a Binance trade archive with its side:
+// negative when the buyer is not the maker. The side is read in the is_buyer_maker column, at index 5 if the archive has no header.
+var GenericTradeQuantityDataFilter = func(data string, line []string, header map[string]int) (string, error) {
+	idx, ok := header["is_buyer_maker"]
+	if !ok {
+		idx = 5
+	}
+	if idx >= len(line) {
+		return "", errors.New("missing is_buyer_maker")
+	}
+	b, err := strconv.ParseBool(strings.TrimSpace(line[idx]))
+	if err != nil {
+		return "", err
+	}
+	if !b {
+		return "-" + data, nil
+	}
+	return data, nil
+}
+
 type ArchiveDataTree struct {
 	ConsistencyMaxLookbackDays int
 	Time                       AssetBranch
 	Columns                    []AssetBranch
+
+	// if set, the archive also contains the asset for arguments not listed in Columns (ex: any percentage of the book depth),
+	// its branch being built from the arguments (see Branch)
+	ParametricAsset  AssetType
+	ParametricBranch func(arguments []string) (AssetBranch, error)
 }
 
 type AssetBranch struct {
@@ -78,6 +56,30 @@ type AssetBranch struct {
 
 	DataFilter func(data string, line []string, header map[string]int) (string, error)
 	Asset      AssetType
+	// arguments of the asset, in canonical form (ex: the percentage of book_depth)
+	Arguments []string
+}
+
+// Address returns the address of the asset of the branch in the set.
+func (ab AssetBranch) Address(setID []string) AssetAddress {
+	return AssetAddressParsed{SetID: setID, AssetType: ab.Asset, Arguments: ab.Arguments}.BuildAddress()
+}
+
+// Branch returns the branch of the tree parsing the asset of the address (its set ID is ignored).
+func (tree *ArchiveDataTree) Branch(address AssetAddressParsed) (AssetBranch, error) {
+	asset := AssetAddressParsed{AssetType: address.AssetType, Arguments: address.Arguments}
+	for _, branch := range tree.Columns {
+		if branch.Address(nil) == asset.BuildAddress() {
+			return branch, nil
+		}
+	}
+	if tree.ParametricBranch != nil {
+		unaliased := asset.Canonical().Unaliased()
+		if unaliased.AssetType == tree.ParametricAsset {
+			return tree.ParametricBranch(unaliased.Arguments)
+		}
+	}
+	return AssetBranch{}, fmt.Errorf("asset %s is not in the archive", address.AssetType)
 }
 
 var BINANCE_SPOT_TRADE_ARCHIVE_TREE = ArchiveDataTree{
@@ -96,17 +98,8 @@ var BINANCE_SPOT_TRADE_ARCHIVE_TREE = ArchiveDataTree{
 		{
 			OriginColumnTitle: "qty",
 			OriginColumnIndex: 2,
-			DataFilter: func(data string, line []string, header map[string]int) (string, error) {
-				b, err := strconv.ParseBool(line[5])
-				if err != nil {
-					return "", err
-				}
-				if !b {
-					return "-" + data, nil
-				}
-				return data, nil
-			},
-			Asset: Asset.SPOT_VOLUME,
+			DataFilter:        GenericTradeQuantityDataFilter,
+			Asset:             Asset.SPOT_VOLUME,
 		},
 	},
 }
@@ -127,17 +120,8 @@ var BINANCE_FUTURES_TRADE_ARCHIVE_TREE = ArchiveDataTree{
 		{
 			OriginColumnTitle: "qty",
 			OriginColumnIndex: 2,
-			DataFilter: func(data string, line []string, header map[string]int) (string, error) {
-				b, err := strconv.ParseBool(line[5])
-				if err != nil {
-					return "", err
-				}
-				if !b {
-					return "-" + data, nil
-				}
-				return data, nil
-			},
-			Asset: Asset.FUTURES_VOLUME,
+			DataFilter:        GenericTradeQuantityDataFilter,
+			Asset:             Asset.FUTURES_VOLUME,
 		},
 	},
 }
@@ -149,68 +133,9 @@ var BINANCE_BOOK_DEPTH_ARCHIVE_TREE = ArchiveDataTree{
 		OriginColumnIndex: 0,
 		DataFilter:        GenericTimeDataFilter,
 	},
-	Columns: []AssetBranch{
-		{
-			OriginColumnTitle: "depth",
-			OriginColumnIndex: 2,
-			Asset:             Asset.BOOK_DEPTH_M1,
-			DataFilter:        GenericBookDepthDataFilter(Asset.BOOK_DEPTH_M1),
-		},
-		{
-			OriginColumnTitle: "depth",
-			OriginColumnIndex: 2,
-			Asset:             Asset.BOOK_DEPTH_M2,
-			DataFilter:        GenericBookDepthDataFilter(Asset.BOOK_DEPTH_M2),
-		},
-		{
-			OriginColumnTitle: "depth",
-			OriginColumnIndex: 2,
-			Asset:             Asset.BOOK_DEPTH_M3,
-			DataFilter:        GenericBookDepthDataFilter(Asset.BOOK_DEPTH_M3),
-		},
-		{
-			OriginColumnTitle: "depth",
-			OriginColumnIndex: 2,
-			Asset:             Asset.BOOK_DEPTH_M4,
-			DataFilter:        GenericBookDepthDataFilter(Asset.BOOK_DEPTH_M4),
-		},
-		{
-			OriginColumnTitle: "depth",
-			OriginColumnIndex: 2,
-			Asset:             Asset.BOOK_DEPTH_M5,
-			DataFilter:        GenericBookDepthDataFilter(Asset.BOOK_DEPTH_M5),
-		},
-		{
-			OriginColumnTitle: "depth",
-			OriginColumnIndex: 2,
-			Asset:             Asset.BOOK_DEPTH_P1,
-			DataFilter:        GenericBookDepthDataFilter(Asset.BOOK_DEPTH_P1),
-		},
-		{
-			OriginColumnTitle: "depth",
-			OriginColumnIndex: 2,
-			Asset:             Asset.BOOK_DEPTH_P2,
-			DataFilter:        GenericBookDepthDataFilter(Asset.BOOK_DEPTH_P2),
-		},
-		{
-			OriginColumnTitle: "depth",
-			OriginColumnIndex: 2,
-			Asset:             Asset.BOOK_DEPTH_P3,
-			DataFilter:        GenericBookDepthDataFilter(Asset.BOOK_DEPTH_P3),
-		},
-		{
-			OriginColumnTitle: "depth",
-			OriginColumnIndex: 2,
-			Asset:             Asset.BOOK_DEPTH_P4,
-			DataFilter:        GenericBookDepthDataFilter(Asset.BOOK_DEPTH_P4),
-		},
-		{
-			OriginColumnTitle: "depth",
-			OriginColumnIndex: 2,
-			Asset:             Asset.BOOK_DEPTH_P5,
-			DataFilter:        GenericBookDepthDataFilter(Asset.BOOK_DEPTH_P5),
-		},
-	},
+	Columns:          bookDepthBranches(),
+	ParametricAsset:  Asset.BOOK_DEPTH,
+	ParametricBranch: bookDepthParametricBranch,
 }
 
 var BINANCE_FUTURES_METRICS_ARCHIVE_TREE = ArchiveDataTree{
@@ -62,9 +62,13 @@ func (at ArchiveType) GetTargetedAssets() []AssetType {
 		log.Fatal("archive tree not found")
 		return nil
 	}
-	return lo.Map(tree.Columns, func(ab AssetBranch, index int) AssetType {
+	ret := lo.Map(tree.Columns, func(ab AssetBranch, index int) AssetType {
 		return ab.Asset
 	})
+	if tree.ParametricBranch != nil {
+		ret = append(ret, tree.ParametricAsset)
+	}
+	return lo.Uniq(ret)
 }
 
 func (assetType AssetType) GetRequiredArchiveType() *ArchiveType {
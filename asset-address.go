@@ -3,7 +3,6 @@ package pcommon
 import (
 	"crypto"
 	"fmt"
-	"strconv"
 	"strings"
 
 	"github.com/samber/lo"
@@ -22,7 +21,8 @@ func (adp AssetAddressParsed) IsValid() error {
 	}
 
 	//check asset type exists
-	if _, ok := AssetTypeMap[string(adp.AssetType)]; !ok {
+	config, ok := GetAssetConfig(adp.AssetType)
+	if !ok {
 		return fmt.Errorf("asset type is invalid")
 	}
 
@@ -30,21 +30,51 @@ func (adp AssetAddressParsed) IsValid() error {
 	for at := range ArchivesIndex {
 		for _, assetType := range at.GetTargetedAssets() {
 			if adp.AssetType == assetType {
-				if adp.HasArguments() || adp.HasDependencies() {
-					return fmt.Errorf("asset %s has dependencies or arguments but it should not", adp.AssetType)
+				if adp.HasDependencies() {
+					return fmt.Errorf("asset %s has dependencies but it should not", adp.AssetType)
 				}
 			}
 		}
 	}
 
-	config := DEFAULT_ASSETS[adp.AssetType]
-	if len(config.RequiredArgumentTypes) != len(adp.Arguments) {
+	if len(config.RequiredArguments) != len(adp.Arguments) {
 		return fmt.Errorf("invalid number of arguments")
 	}
-	if len(config.RequiredDependencyDataTypes) != len(adp.Dependencies) {
+	if len(config.RequiredDependencies) != len(adp.Dependencies) && !(config.VariadicDependencies && len(adp.Dependencies) > len(config.RequiredDependencies)) {
 		return fmt.Errorf("invalid number of dependencies")
 	}
 
+	for i, arg := range adp.Arguments {
+		if err := config.RequiredArguments[i].Validate(arg); err != nil {
+			return fmt.Errorf("invalid argument for %s: %s", adp.AssetType, err)
+		}
+	}
+
+	// indicators check the arguments depending on each other (ex: a fast period lower than a slow one) in Init
+	var argumentColumns [][]ColumnName
+	if factory := getIndicatorFactory(adp.AssetType); factory != nil {
+		indicator := factory()
+		args, err := parseIndicatorArguments(config.RequiredArguments, adp.Arguments)
+		if err == nil {
+			err = indicator.Init(args)
+		}
+		if err != nil {
+			return fmt.Errorf("invalid argument for %s: %s", adp.AssetType, err)
+		}
+		if indicator, ok := indicator.(ArgumentDependenciesIndicator); ok {
+			argumentColumns = indicator.DependencyColumns()
+			if len(argumentColumns) != len(adp.Dependencies) {
+				return fmt.Errorf("invalid number of dependencies for %s: its arguments use %d", adp.AssetType, len(argumentColumns))
+			}
+		}
+	}
+
+	// an asset comparing a dependency with itself is always constant
+	if config.AllowCrossSetDependencies && len(lo.Uniq(adp.Canonical().Dependencies)) != len(adp.Dependencies) {
+		return fmt.Errorf("dependencies of %s must be different", adp.AssetType)
+	}
+
+	dependencies := make([]AssetAddressParsed, len(adp.Dependencies))
 	for i, depAddr := range adp.Dependencies {
 		dep, err := depAddr.Parse()
 		if err != nil {
@@ -53,31 +83,22 @@ func (adp AssetAddressParsed) IsValid() error {
 		if err := dep.IsValid(); err != nil {
 			return err
 		}
-		if config.RequiredDependencyDataTypes[i] > 0 {
-			if DEFAULT_ASSETS[dep.AssetType].DataType != config.RequiredDependencyDataTypes[i] {
-				return fmt.Errorf("invalid dependency type")
-			}
+		dependencies[i] = *dep
+		if !sameSetID(dep.SetID, adp.SetID) && (!config.AllowCrossSetDependencies || i == 0) {
+			return fmt.Errorf("invalid dependency %d for %s: %s belongs to another set", i, adp.AssetType, dep.AssetType)
+		}
+		constraint := config.dependencyConstraint(i)
+		if i < len(argumentColumns) {
+			constraint.Columns = append(append([]ColumnName{}, constraint.Columns...), argumentColumns[i]...)
+		}
+		if err := constraint.Check(*dep, config, adp.Arguments); err != nil {
+			return fmt.Errorf("invalid dependency %d for %s: %s", i, adp.AssetType, err)
 		}
 	}
 
-	for i, arg := range adp.Arguments {
-		requiredArgType := config.RequiredArgumentTypes[i].String()
-		if strings.HasPrefix(requiredArgType, "int") {
-			if _, err := strconv.ParseInt(arg, 10, 64); err != nil {
-				return fmt.Errorf("invalid argument type")
-			}
-		} else if strings.HasPrefix(requiredArgType, "bool") {
-			if _, err := strconv.ParseBool(arg); err != nil {
-				return fmt.Errorf("invalid argument type")
-			}
-		} else if strings.HasPrefix(requiredArgType, "float") {
-			if _, err := strconv.ParseFloat(arg, 64); err != nil {
-				return fmt.Errorf("invalid argument type")
-			}
-		} else {
-			if requiredArgType != "string" {
-				return fmt.Errorf("not supported argument type")
-			}
+	if config.CheckDependencies != nil {
+		if err := config.CheckDependencies(dependencies); err != nil {
+			return fmt.Errorf("invalid dependencies for %s: %s", adp.AssetType, err)
 		}
 	}
 
@@ -86,10 +107,17 @@ func (adp AssetAddressParsed) IsValid() error {
 }
 
 // setID is a list of alpa-numeric strings (string array is for easier comparison)
+// the address is built from the canonical form of the parsed address (see Canonical)
 func (adp AssetAddressParsed) BuildAddress() AssetAddress {
+	return adp.Canonical().buildAddress()
+}
+
+func (adp AssetAddressParsed) buildAddress() AssetAddress {
 
 	setIDStr := strings.ToLower(strings.Join(adp.SetID, "_"))
-	argumentsStr := strings.Join(adp.Arguments, "_")
+	argumentsStr := strings.Join(lo.Map(adp.Arguments, func(arg string, _ int) string {
+		return argumentEscaper.Replace(arg)
+	}), "_")
 
 	dependenciesSliceStr := make([]string, len(adp.Dependencies))
 	for i, dep := range adp.Dependencies {
@@ -141,7 +169,14 @@ func (aap AssetAddressParsed) IDString() string {
 	return SetIDToString(aap.SetID)
 }
 
+// BuildCSVColumnName returns the column name of the asset in a CSV export (see ParseCSVColumnName).
+// Without includeSetID, the set IDs are only written for the dependencies belonging to another set than their parent,
+// so that the column can still be parsed back.
 func (aap AssetAddressParsed) BuildCSVColumnName(includeSetID bool) (string, error) {
+	return aap.buildCSVColumnName(includeSetID, nil)
+}
+
+func (aap AssetAddressParsed) buildCSVColumnName(includeSetID bool, parentSetID []string) (string, error) {
 	dep := []AssetAddressParsed{}
 
 	for _, depAddr := range aap.Dependencies {
@@ -155,7 +190,7 @@ func (aap AssetAddressParsed) BuildCSVColumnName(includeSetID bool) (string, err
 	argumentStr := strings.Join(aap.Arguments, "|")
 	var err2 error = nil
 	depStr := strings.Join(lo.Map(dep, func(d AssetAddressParsed, i int) string {
-		str, err := d.BuildCSVColumnName(includeSetID)
+		str, err := d.buildCSVColumnName(includeSetID, aap.SetID)
 		if err != nil {
 			err2 = err
 			return ""
@@ -168,7 +203,7 @@ func (aap AssetAddressParsed) BuildCSVColumnName(includeSetID bool) (string, err
 	}
 
 	ret := []string{}
-	if includeSetID {
+	if includeSetID || (parentSetID != nil && !sameSetID(aap.SetID, parentSetID)) {
 		ret = append(ret, SetIDToString(aap.SetID))
 	}
 	ret = append(ret, string(aap.AssetType))
@@ -230,6 +265,9 @@ func (address AssetAddress) Parse() (*AssetAddressParsed, error) {
 	var arguments []string = nil
 	if argumentsStr != "" {
 		arguments = strings.Split(argumentsStr, "_")
+		for i, arg := range arguments {
+			arguments[i] = argumentUnescaper.Replace(arg)
+		}
 	}
 
 	return &AssetAddressParsed{
@@ -241,12 +279,21 @@ func (address AssetAddress) Parse() (*AssetAddressParsed, error) {
 }
 
 func (address AssetAddress) Sha256() []byte {
+	//equivalent addresses share the same sha
+	if canonical, err := address.Canonical(); err == nil {
+		address = canonical
+	}
+
 	//createe a sha of the string address
 	h := crypto.SHA256.New()
 	h.Write([]byte(address))
 	return h.Sum(nil)
 }
 
+// the characters separating the parts of an address are escaped in arguments (ex: the "_" of a column name in a formula)
+var argumentEscaper = strings.NewReplacer("%", "%25", "_", "%5F", ";", "%3B", "=", "%3D", "[", "%5B", "]", "%5D")
+var argumentUnescaper = strings.NewReplacer("%25", "%", "%5F", "_", "%3B", ";", "%3D", "=", "%5B", "[", "%5D", "]")
+
 func splitMainParts(address string) ([]string, error) {
 	var parts []string
 	var currentPart strings.Builder
@@ -328,3 +375,7 @@ func splitDependencies(dependenciesStr string) ([]string, error) {
 	parts = append(parts, currentPart.String())
 	return parts, nil
 }
+
+func sameSetID(a, b []string) bool {
+	return strings.EqualFold(strings.Join(a, "_"), strings.Join(b, "_"))
+}
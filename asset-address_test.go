@@ -90,3 +90,271 @@ func TestBuildAssetAddress(t *testing.T) {
 	assert.Nil(t, err)
 	assert.Equal(t, blabla, *blablaParsed)
 }
+
+func TestParseAssetNotations(t *testing.T) {
+	price := AssetAddressParsed{
+		SetID:     []string{"btc", "usdt"},
+		AssetType: Asset.SPOT_PRICE,
+	}
+	sma := AssetAddressParsed{
+		SetID:        []string{"btc", "usdt"},
+		AssetType:    Asset.SMA,
+		Dependencies: []AssetAddress{price.BuildAddress()},
+		Arguments:    []string{"close", "14"},
+	}
+	rsi := AssetAddressParsed{
+		SetID:        []string{"btc", "usdt"},
+		AssetType:    Asset.RSI2,
+		Dependencies: []AssetAddress{sma.BuildAddress()},
+		Arguments:    []string{"value", "21"},
+	}
+
+	notation, err := rsi.Notation()
+	assert.Nil(t, err)
+	assert.Equal(t, "BTC/USDT.RSI2(value, 21)[BTC/USDT.SMA(close, 14)[BTC/USDT.SPOT_PRICE]]", notation)
+
+	parsed, err := ParseAssetNotation(notation)
+	assert.Nil(t, err)
+	assert.Equal(t, rsi, *parsed)
+	assert.Nil(t, parsed.IsValid())
+
+	// dependencies without set ID inherit the set of their parent
+	parsed, err = ParseAssetNotation("btc/usdt.sma( close , 14 )[spot_price]")
+	assert.Nil(t, err)
+	assert.Equal(t, sma, *parsed)
+
+	column, err := rsi.BuildCSVColumnName(true)
+	assert.Nil(t, err)
+	assert.Equal(t, "btcusdt.rsi2(value|21)[btcusdt.sma(close|14)[btcusdt.spot_price]]", column)
+	parsed, err = ParseCSVColumnName(column, price.SetID)
+	assert.Nil(t, err)
+	assert.Equal(t, rsi, *parsed)
+
+	column, err = rsi.BuildCSVColumnName(false)
+	assert.Nil(t, err)
+	parsed, err = ParseCSVColumnName(column, price.SetID)
+	assert.Nil(t, err)
+	assert.Equal(t, rsi, *parsed)
+
+	// dependencies of another set keep their set ID
+	ethPrice := AssetAddressParsed{SetID: []string{"eth", "usdt"}, AssetType: Asset.SPOT_PRICE}
+	correlation := AssetAddressParsed{
+		SetID:        price.SetID,
+		AssetType:    Asset.CORRELATION,
+		Dependencies: []AssetAddress{price.BuildAddress(), ethPrice.BuildAddress()},
+		Arguments:    []string{"close", "30", "true"},
+	}
+	column, err = correlation.BuildCSVColumnName(false)
+	assert.Nil(t, err)
+	assert.Equal(t, "correlation(close|30|true)[spot_price|ethusdt.spot_price]", column)
+	parsed, err = ParseCSVColumnName(column, price.SetID, ethPrice.SetID)
+	assert.Nil(t, err)
+	assert.Equal(t, correlation, *parsed)
+
+	_, err = ParseCSVColumnName("ethusdt.spot_price", price.SetID)
+	assert.NotNil(t, err)
+	_, err = ParseAssetNotation("BTC/USDT.SMA(close, 14[BTC/USDT.SPOT_PRICE]")
+	assert.NotNil(t, err)
+	_, err = ParseAssetNotation("BTC/USDT.SMA(close)[BTC/USDT.SPOT_PRICE]")
+	assert.NotNil(t, err)
+}
+
+func TestAssetArgumentSchemas(t *testing.T) {
+	price := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.SPOT_PRICE}
+	sma := AssetAddressParsed{
+		SetID:        []string{"btc", "usdt"},
+		AssetType:    Asset.SMA,
+		Dependencies: []AssetAddress{price.BuildAddress()},
+		Arguments:    []string{"close", "14"},
+	}
+	assert.Nil(t, sma.IsValid())
+
+	sma.Arguments = []string{"close", "0"}
+	assert.EqualError(t, sma.IsValid(), "invalid argument for sma: argument period must be greater than or equal to 1")
+	sma.Arguments = []string{"close", "-3"}
+	assert.NotNil(t, sma.IsValid())
+	sma.Arguments = []string{"close", "abc"}
+	assert.EqualError(t, sma.IsValid(), "invalid argument for sma: argument period must be an integer")
+	// huge periods must not allocate the buffers of the indicator
+	for _, period := range []string{"1000000000000000000", "9223372036854775807", "1000001"} {
+		sma.Arguments = []string{"close", period}
+		assert.EqualError(t, sma.IsValid(), "invalid argument for sma: argument period must be lower than or equal to 1000000")
+	}
+	schema := ArgumentSchema{Name: "count", Type: ARG_INT}
+	assert.NotNil(t, schema.Validate("1000000000"), "integers are bounded without a maximum in the schema")
+
+	// non-finite numbers pass no bound
+	bollinger := AssetAddressParsed{SetID: price.SetID, AssetType: Asset.BOLLINGER, Dependencies: []AssetAddress{price.BuildAddress()}, Arguments: DEFAULT_ASSETS[Asset.BOLLINGER].DefaultArguments()}
+	assert.Nil(t, bollinger.IsValid())
+	for _, v := range []string{"NaN", "nan", "Inf", "+Inf", "-Inf", "infinity"} {
+		bollinger.Arguments[len(bollinger.Arguments)-1] = v
+		assert.NotNil(t, bollinger.IsValid(), v)
+		book, err := AssetAddress("btc_usdt;book_depth;[];" + v).Parse()
+		assert.Nil(t, err)
+		assert.NotNil(t, book.IsValid(), v)
+	}
+
+	config := DEFAULT_ASSETS[Asset.SMA]
+	assert.Equal(t, []string{"close", "20"}, config.DefaultArguments())
+	assert.Equal(t, "column", config.RequiredArguments[0].Name)
+	assert.Equal(t, "period", config.RequiredArguments[1].Name)
+
+	for _, a := range DEFAULT_ASSETS.JSON() {
+		if a.AssetType == Asset.SMA {
+			assert.Equal(t, []string{"string", "int64"}, a.ArgumentTypes)
+			assert.Equal(t, config.RequiredArguments, a.Arguments)
+		}
+	}
+}
+
+func TestAssetDependencyConstraints(t *testing.T) {
+	price := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.SPOT_PRICE}
+	volume := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.SPOT_VOLUME}
+
+	sma := AssetAddressParsed{
+		SetID:        []string{"btc", "usdt"},
+		AssetType:    Asset.SMA,
+		Dependencies: []AssetAddress{volume.BuildAddress()},
+		Arguments:    []string{"plus", "14"},
+	}
+	assert.Nil(t, sma.IsValid())
+
+	sma.Arguments = []string{"close", "14"}
+	assert.EqualError(t, sma.IsValid(), "invalid dependency 0 for sma: column close not found in spot_volume (available: plus, minus, plus_average, minus_average, plus_median, minus_median, plus_count, minus_count)")
+	sma.Arguments = []string{"time", "14"}
+	assert.NotNil(t, sma.IsValid())
+
+	rsi := AssetAddressParsed{
+		SetID:        []string{"btc", "usdt"},
+		AssetType:    Asset.RSI,
+		Dependencies: []AssetAddress{volume.BuildAddress()},
+		Arguments:    []string{"14"},
+	}
+	assert.EqualError(t, rsi.IsValid(), "invalid dependency 0 for rsi: spot_volume data type is qty but must be one of: unit")
+	rsi.Dependencies = []AssetAddress{price.BuildAddress()}
+	assert.Nil(t, rsi.IsValid())
+}
+
+func TestCanonicalAssetAddress(t *testing.T) {
+	canonical := AssetAddress("btc_usdt;sma;[btc_usdt;spot_price;[];];close_14")
+
+	equivalents := []AssetAddress{
+		"btc_usdt;sma;[btc_usdt;spot_price;[];];close_14",
+		"BTC_usdt;SMA;[btc_USDT;Spot_Price;[];];CLOSE_014",
+		" btc_usdt ;sma;[btc_usdt;spot_price;[];]; close _ 14 ",
+	}
+	for _, address := range equivalents {
+		c, err := address.Canonical()
+		assert.Nil(t, err)
+		assert.Equal(t, canonical, c)
+		assert.Equal(t, canonical.Sha256(), address.Sha256())
+
+		parsed, err := address.Parse()
+		assert.Nil(t, err)
+		assert.Equal(t, canonical, parsed.BuildAddress())
+	}
+
+	set := SetSettings{
+		ID:       []string{"btc", "usdt"},
+		Settings: map[string]int64{"binance": 1},
+		Assets: []AssetSettings{
+			{Address: AssetAddressParsedWithoutSetID{AssetType: Asset.SPOT_PRICE}, MinDataDate: "2020-05-05"},
+			{Address: AssetAddressParsedWithoutSetID{AssetType: Asset.SMA, Dependencies: []AssetAddress{"btc_usdt;spot_price;[];"}, Arguments: []string{"close", "14"}}, MinDataDate: "2020-05-05"},
+		},
+	}
+	assert.Nil(t, set.IsValid())
+	assert.True(t, set.ContainsAssetAddress(equivalents[1]))
+
+	set.Assets = append(set.Assets, AssetSettings{
+		Address:     AssetAddressParsedWithoutSetID{AssetType: Asset.SMA, Dependencies: []AssetAddress{"BTC_USDT;spot_price;[];"}, Arguments: []string{"close", "014"}},
+		MinDataDate: "2020-05-05",
+	})
+	assert.EqualError(t, set.IsValid(), "duplicate asset address: "+string(canonical))
+}
+
+func TestBookDepthAsset(t *testing.T) {
+	legacy := AssetAddress("btc_usdt;bd-m2;[];")
+	c, err := AssetAddress("BTC_USDT;BOOK_DEPTH;[];-2.0").Canonical()
+	assert.Nil(t, err)
+	assert.Equal(t, legacy, c)
+	for _, address := range []AssetAddress{legacy, "btc_usdt;book_depth;[];-2", "btc_usdt;book_depth;[];-2.0"} {
+		c, err := address.Canonical()
+		assert.Nil(t, err)
+		assert.Equal(t, legacy, c, "the legacy asset type stays the canonical form")
+		assert.Equal(t, legacy.Sha256(), address.Sha256())
+
+		parsed, err := address.Parse()
+		assert.Nil(t, err)
+		assert.Nil(t, parsed.IsValid())
+		percentage, err := parsed.BookDepthPercentage()
+		assert.Nil(t, err)
+		assert.Equal(t, -2.0, percentage)
+		assert.Equal(t, Asset.BOOK_DEPTH, parsed.Unaliased().AssetType)
+	}
+
+	parametric := AssetAddress("btc_usdt;book_depth;[];0.2")
+	c, err = AssetAddress("btc_usdt;book_depth;[];00.20").Canonical()
+	assert.Nil(t, err)
+	assert.Equal(t, parametric, c)
+	parsed, _ := parametric.Parse()
+	assert.Nil(t, parsed.IsValid())
+	_, err = AssetAddressParsed{AssetType: Asset.SPOT_PRICE}.BookDepthPercentage()
+	assert.NotNil(t, err)
+
+	// the market price itself has no depth
+	for _, zero := range []AssetAddress{"btc_usdt;book_depth;[];0", "btc_usdt;book_depth;[];-0.0"} {
+		parsed, err := zero.Parse()
+		assert.Nil(t, err)
+		assert.NotNil(t, parsed.IsValid(), zero)
+	}
+	set := SetSettings{
+		ID:       []string{"btc", "usdt"},
+		Settings: map[string]int64{"binance": 1},
+		Assets: []AssetSettings{
+			{Address: AssetAddressParsedWithoutSetID{AssetType: Asset.BOOK_DEPTH, Arguments: []string{"0.2"}}, MinDataDate: "2023-01-01"},
+		},
+	}
+	assert.Nil(t, set.IsValid())
+	set.Assets[0].Address.Arguments = []string{"0"}
+	assert.NotNil(t, set.IsValid())
+
+	tree := ArchivesIndex[BINANCE_BOOK_DEPTH]
+	header := map[string]int{"timestamp": 0, "percentage": 1, "depth": 2, "notional": 3}
+	branch, err := tree.Branch(*parsed)
+	assert.Nil(t, err)
+	assert.Equal(t, parametric, branch.Address([]string{"btc", "usdt"}))
+	v, err := branch.DataFilter("12.5", []string{"2023-01-01 00:00:08", "0.20", "12.5", "200"}, header)
+	assert.Nil(t, err)
+	assert.Equal(t, "12.5", v)
+	v, err = branch.DataFilter("12.5", []string{"2023-01-01 00:00:08", "-0.20", "12.5", "200"}, header)
+	assert.Nil(t, err)
+	assert.Equal(t, "", v)
+
+	legacyParsed, _ := legacy.Parse()
+	branch, err = tree.Branch(*legacyParsed)
+	assert.Nil(t, err)
+	assert.Equal(t, Asset.BOOK_DEPTH_M2, branch.Asset)
+	assert.Empty(t, branch.Arguments)
+	assert.Equal(t, BINANCE_BOOK_DEPTH, *Asset.BOOK_DEPTH.GetRequiredArchiveType())
+	assert.Equal(t, BINANCE_BOOK_DEPTH, *Asset.BOOK_DEPTH_P5.GetRequiredArchiveType())
+
+	_, err = tree.Branch(AssetAddressParsed{AssetType: Asset.SPOT_PRICE})
+	assert.NotNil(t, err)
+
+	// deprecated API of the legacy asset types
+	percentage, err := Asset.BOOK_DEPTH_M3.GetBookDepthAssetPercentage()
+	assert.Nil(t, err)
+	assert.Equal(t, -3, percentage)
+	percentage, err = GetBookDepthAssetPercentage(Asset.BOOK_DEPTH_P5)
+	assert.Nil(t, err)
+	assert.Equal(t, 5, percentage)
+	_, err = Asset.BOOK_DEPTH.GetBookDepthAssetPercentage()
+	assert.NotNil(t, err)
+	_, err = Asset.SPOT_PRICE.GetBookDepthAssetPercentage()
+	assert.NotNil(t, err)
+	v, err = GenericBookDepthDataFilter(Asset.BOOK_DEPTH_M2)("12.5", []string{"2023-01-01 00:00:08", "-2.00", "12.5", "200"}, header)
+	assert.Nil(t, err)
+	assert.Equal(t, "12.5", v)
+	_, err = GenericBookDepthDataFilter(Asset.SPOT_PRICE)("12.5", []string{"2023-01-01 00:00:08", "-2.00", "12.5", "200"}, header)
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,166 @@
+package pcommon
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/samber/lo"
+)
+
+type ArgumentType string
+
+const ARG_INT ArgumentType = "int"
+const ARG_FLOAT ArgumentType = "float"
+const ARG_BOOL ArgumentType = "bool"
+const ARG_STRING ArgumentType = "string"
+
+// ArgumentSchema describes an argument of an asset address.
+type ArgumentSchema struct {
+	Name    string       `json:"name"`
+	Type    ArgumentType `json:"type"`
+	Default string       `json:"default"`
+
+	// bounds of numeric arguments (inclusive)
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+	// if true, numeric arguments cannot be 0
+	NonZero bool `json:"non_zero,omitempty"`
+
+	// if not empty, the argument must be one of these values
+	AllowedValues []string `json:"allowed_values,omitempty"`
+
+	Description string `json:"description"`
+}
+
+// maxIntArgument bounds the integer arguments, periods sizing the buffers of the indicators.
+const maxIntArgument = 1000000
+
+func argumentBound(v float64) *float64 {
+	return &v
+}
+
+func columnArgument(defaultColumn ColumnName) ArgumentSchema {
+	return ArgumentSchema{
+		Name:        "column",
+		Type:        ARG_STRING,
+		Default:     string(defaultColumn),
+		Description: "Column of the dependency the indicator is computed on.",
+	}
+}
+
+func periodArgument(defaultPeriod int64, min int64) ArgumentSchema {
+	return intArgument("period", defaultPeriod, min, "Number of values the indicator is computed on.")
+}
+
+func floatArgument(name string, defaultValue float64, min float64, description string) ArgumentSchema {
+	return ArgumentSchema{
+		Name:        name,
+		Type:        ARG_FLOAT,
+		Default:     strconv.FormatFloat(defaultValue, 'f', -1, 64),
+		Min:         argumentBound(min),
+		Description: description,
+	}
+}
+
+func intArgument(name string, defaultValue int64, min int64, description string) ArgumentSchema {
+	return ArgumentSchema{
+		Name:        name,
+		Type:        ARG_INT,
+		Default:     strconv.FormatInt(defaultValue, 10),
+		Min:         argumentBound(float64(min)),
+		Max:         argumentBound(maxIntArgument),
+		Description: description,
+	}
+}
+
+// Parse converts the argument into an int64, a float64, a bool or a string depending on the schema type
+// and checks its bounds and allowed values.
+// If the argument is invalid, the zero value of the type is returned along with the error.
+func (s ArgumentSchema) Parse(arg string) (interface{}, error) {
+	switch s.Type {
+	case ARG_INT:
+		v, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil {
+			return int64(0), fmt.Errorf("argument %s must be an integer", s.Name)
+		}
+		if err := s.checkBounds(float64(v)); err != nil {
+			return int64(0), err
+		}
+		// schemas without a maximum are bounded too
+		if v > maxIntArgument {
+			return int64(0), fmt.Errorf("argument %s must be lower than or equal to %d", s.Name, maxIntArgument)
+		}
+		return v, s.checkAllowedValues(arg)
+	case ARG_FLOAT:
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return float64(0), fmt.Errorf("argument %s must be a number", s.Name)
+		}
+		// NaN passes every bound
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return float64(0), fmt.Errorf("argument %s must be a finite number", s.Name)
+		}
+		if err := s.checkBounds(v); err != nil {
+			return float64(0), err
+		}
+		return v, s.checkAllowedValues(arg)
+	case ARG_BOOL:
+		v, err := strconv.ParseBool(arg)
+		if err != nil {
+			return false, fmt.Errorf("argument %s must be a boolean", s.Name)
+		}
+		return v, nil
+	case ARG_STRING:
+		if arg == "" {
+			return "", fmt.Errorf("argument %s is empty", s.Name)
+		}
+		return arg, s.checkAllowedValues(arg)
+	}
+	return nil, fmt.Errorf("argument %s has an unsupported type %s", s.Name, s.Type)
+}
+
+func (s ArgumentSchema) Validate(arg string) error {
+	_, err := s.Parse(arg)
+	return err
+}
+
+func (s ArgumentSchema) checkBounds(v float64) error {
+	if s.Min != nil && v < *s.Min {
+		return fmt.Errorf("argument %s must be greater than or equal to %s", s.Name, Format.Float(*s.Min, -1))
+	}
+	if s.Max != nil && v > *s.Max {
+		return fmt.Errorf("argument %s must be lower than or equal to %s", s.Name, Format.Float(*s.Max, -1))
+	}
+	if s.NonZero && v == 0 {
+		return fmt.Errorf("argument %s cannot be 0", s.Name)
+	}
+	return nil
+}
+
+func (s ArgumentSchema) checkAllowedValues(arg string) error {
+	if len(s.AllowedValues) > 0 && lo.IndexOf(s.AllowedValues, arg) == -1 {
+		return fmt.Errorf("argument %s must be one of %v", s.Name, s.AllowedValues)
+	}
+	return nil
+}
+
+// legacyTypeName returns the name of the go type of the argument, as exposed before argument schemas existed.
+func (t ArgumentType) legacyTypeName() string {
+	switch t {
+	case ARG_INT:
+		return "int64"
+	case ARG_FLOAT:
+		return "float64"
+	}
+	return string(t)
+}
+
+// DefaultArguments returns the default value of each argument of the asset.
+func (c AssetStateConfig) DefaultArguments() []string {
+	ret := make([]string, len(c.RequiredArguments))
+	for i, arg := range c.RequiredArguments {
+		ret[i] = arg.Default
+	}
+	return ret
+}
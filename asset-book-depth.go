@@ -0,0 +1,197 @@
+package pcommon
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/samber/lo"
+)
+
+/*
+	BOOK_DEPTH is the liquidity within a percentage of the market price: below it (bid side) for negative percentages,
+	above it (ask side) for positive ones. The percentage is the argument of the asset, ex: book_depth(-0.2).
+
+	The ten asset types bd-m5 .. bd-m1 and bd-p1 .. bd-p5 are kept as aliases of BOOK_DEPTH from -5% to 5%.
+	They remain the canonical form of these percentages (see Canonical), so that the addresses of the stored data do not change.
+*/
+
+// bookDepthAliases are the legacy book depth asset types with the percentage argument they stand for.
+var bookDepthAliases = map[AssetType]string{
+	Asset.BOOK_DEPTH_M1: "-1",
+	Asset.BOOK_DEPTH_M2: "-2",
+	Asset.BOOK_DEPTH_M3: "-3",
+	Asset.BOOK_DEPTH_M4: "-4",
+	Asset.BOOK_DEPTH_M5: "-5",
+	Asset.BOOK_DEPTH_P1: "1",
+	Asset.BOOK_DEPTH_P2: "2",
+	Asset.BOOK_DEPTH_P3: "3",
+	Asset.BOOK_DEPTH_P4: "4",
+	Asset.BOOK_DEPTH_P5: "5",
+}
+
+func bookDepthDecimals(priceUSDA, priceUSDB float64) int8 {
+	return countDivisionsTo(priceUSDA/priceUSDB, 10)
+}
+
+func bookDepthLabel(percentage float64) string {
+	return fmt.Sprintf("Liquidity %+g%% Price", percentage)
+}
+
+func bookDepthDescription(percentage float64) string {
+	side := "above"
+	if percentage < 0 {
+		side = "below"
+	}
+	return fmt.Sprintf("Available liquidity at a price level %g%% %s the current market price on Binance.", math.Abs(percentage), side)
+}
+
+// bookDepthAliasConfig returns the config of a legacy book depth asset type.
+func bookDepthAliasConfig(assetType AssetType, color string) AssetStateConfig {
+	percentage, _ := strconv.ParseFloat(bookDepthAliases[assetType], 64)
+	return AssetStateConfig{
+		SetUpDecimals: bookDepthDecimals,
+		ID:            assetType,
+		DataType:      UNIT,
+		Label:         bookDepthLabel(percentage),
+		Description:   bookDepthDescription(percentage),
+		Color:         color,
+	}
+}
+
+// Unaliased returns the address of the parametric asset type a legacy book depth address stands for,
+// ex: bd-m2 -> book_depth(-2). Other addresses are returned unchanged.
+func (adp AssetAddressParsed) Unaliased() AssetAddressParsed {
+	percentage, ok := bookDepthAliases[adp.AssetType]
+	if !ok || adp.HasArguments() {
+		return adp
+	}
+	adp.AssetType = Asset.BOOK_DEPTH
+	adp.Arguments = []string{percentage}
+	return adp
+}
+
+// aliased returns the legacy book depth address of a canonical book_depth address, if there is one.
+func (adp AssetAddressParsed) aliased() AssetAddressParsed {
+	if adp.AssetType != Asset.BOOK_DEPTH || len(adp.Arguments) != 1 || adp.HasDependencies() {
+		return adp
+	}
+	for alias, percentage := range bookDepthAliases {
+		if adp.Arguments[0] == percentage {
+			adp.AssetType = alias
+			adp.Arguments = nil
+			return adp
+		}
+	}
+	return adp
+}
+
+// BookDepthPercentage returns the percentage of a book depth address, given as book_depth(percentage) or a legacy asset type.
+func (adp AssetAddressParsed) BookDepthPercentage() (float64, error) {
+	unaliased := adp.Unaliased()
+	if unaliased.AssetType != Asset.BOOK_DEPTH {
+		return 0, fmt.Errorf("asset %s is not a book depth", adp.AssetType)
+	}
+	if len(unaliased.Arguments) != 1 {
+		return 0, errors.New("invalid number of arguments")
+	}
+	return parseBookDepthPercentage(unaliased.Arguments[0])
+}
+
+func parseBookDepthPercentage(arg string) (float64, error) {
+	percentage, err := strconv.ParseFloat(arg, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %s", arg)
+	}
+	if percentage == 0 {
+		return 0, errors.New("percentage cannot be 0")
+	}
+	return percentage, nil
+}
+
+// GetBookDepthAssetPercentage returns the percentage of a legacy book depth asset type.
+//
+// Deprecated: use AssetAddressParsed.BookDepthPercentage, which also handles book_depth(percentage).
+func (asset AssetType) GetBookDepthAssetPercentage() (int, error) {
+	if _, ok := bookDepthAliases[asset]; !ok {
+		return 0, errors.New("invalid asset")
+	}
+	percentage, err := AssetAddressParsed{AssetType: asset}.BookDepthPercentage()
+	return int(percentage), err
+}
+
+// GetBookDepthAssetPercentage returns the percentage of a legacy book depth asset type.
+//
+// Deprecated: use AssetAddressParsed.BookDepthPercentage, which also handles book_depth(percentage).
+var GetBookDepthAssetPercentage = func(asset AssetType) (int, error) {
+	return asset.GetBookDepthAssetPercentage()
+}
+
+// GenericBookDepthDataFilter keeps the depth of the lines of a Binance book depth archive at the percentage of a legacy book depth asset type.
+//
+// Deprecated: use BookDepthDataFilter, or the branches of BINANCE_BOOK_DEPTH_ARCHIVE_TREE (see ArchiveDataTree.Branch).
+func GenericBookDepthDataFilter(asset AssetType) func(data string, line []string, header map[string]int) (string, error) {
+	percentage, err := asset.GetBookDepthAssetPercentage()
+	if err != nil {
+		return func(data string, line []string, header map[string]int) (string, error) {
+			return "", err
+		}
+	}
+	return BookDepthDataFilter(float64(percentage))
+}
+
+// BookDepthDataFilter keeps the depth of the lines of a Binance book depth archive at the percentage.
+func BookDepthDataFilter(percentage float64) func(data string, line []string, header map[string]int) (string, error) {
+	return func(data string, line []string, header map[string]int) (string, error) {
+		idx, ok := header["percentage"]
+		if !ok {
+			idx = 1
+		}
+		if idx >= len(line) {
+			return "", errors.New("missing percentage")
+		}
+		linePercentage, err := parseBookDepthPercentage(line[idx])
+		if err != nil {
+			return "", err
+		}
+		if linePercentage == percentage {
+			return data, nil
+		}
+		return "", nil
+	}
+}
+
+// BookDepthBranch returns the branch of the Binance book depth archive of the percentage,
+// its asset being in canonical form (ex: bd-p1 for 1%, book_depth(0.2) for 0.2%).
+func BookDepthBranch(percentage float64) AssetBranch {
+	address := AssetAddressParsed{
+		AssetType: Asset.BOOK_DEPTH,
+		Arguments: []string{strconv.FormatFloat(percentage, 'f', -1, 64)},
+	}.aliased()
+	return AssetBranch{
+		OriginColumnTitle: "depth",
+		OriginColumnIndex: 2,
+		Asset:             address.AssetType,
+		Arguments:         address.Arguments,
+		DataFilter:        BookDepthDataFilter(percentage),
+	}
+}
+
+func bookDepthParametricBranch(arguments []string) (AssetBranch, error) {
+	if len(arguments) != 1 {
+		return AssetBranch{}, errors.New("invalid number of arguments")
+	}
+	percentage, err := parseBookDepthPercentage(arguments[0])
+	if err != nil {
+		return AssetBranch{}, err
+	}
+	return BookDepthBranch(percentage), nil
+}
+
+// bookDepthBranches returns the branches of the percentages published in the Binance book depth archives.
+func bookDepthBranches() []AssetBranch {
+	return lo.Map([]float64{-1, -2, -3, -4, -5, 1, 2, 3, 4, 5}, func(percentage float64, _ int) AssetBranch {
+		return BookDepthBranch(percentage)
+	})
+}
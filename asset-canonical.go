@@ -0,0 +1,87 @@
+package pcommon
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// Canonical returns the address in its canonical form, so that equivalent addresses are equal:
+//   - set IDs and asset type are lowercased
+//   - whitespaces are removed from arguments
+//   - numeric and boolean arguments are formatted the same way (ex: "014" -> "14")
+//   - column arguments are lowercased
+//   - dependencies are canonicalized recursively
+//   - book depths from -5% to 5% use their legacy asset type (ex: book_depth(1) -> bd-p1)
+func (address AssetAddress) Canonical() (AssetAddress, error) {
+	parsed, err := address.Parse()
+	if err != nil {
+		return "", err
+	}
+	return parsed.BuildAddress(), nil
+}
+
+func (adp AssetAddressParsed) Canonical() AssetAddressParsed {
+	ret := AssetAddressParsed{
+		SetID:     make([]string, len(adp.SetID)),
+		AssetType: AssetType(strings.ToLower(strings.TrimSpace(string(adp.AssetType)))),
+	}
+	for i, id := range adp.SetID {
+		ret.SetID[i] = strings.ToLower(strings.TrimSpace(id))
+	}
+
+	config, registered := GetAssetConfig(ret.AssetType)
+	columnArguments := lo.Map(config.RequiredDependencies, func(dc DependencyConstraint, _ int) string {
+		return dc.ColumnArgument
+	})
+
+	if adp.Arguments != nil {
+		ret.Arguments = make([]string, len(adp.Arguments))
+	}
+	for i, arg := range adp.Arguments {
+		arg = strings.Join(strings.Fields(arg), "")
+		if registered && i < len(config.RequiredArguments) {
+			schema := config.RequiredArguments[i]
+			arg = canonicalArgument(schema, arg)
+			if lo.IndexOf(columnArguments, schema.Name) != -1 {
+				arg = strings.ToLower(arg)
+			}
+		}
+		ret.Arguments[i] = arg
+	}
+
+	if adp.Dependencies != nil {
+		ret.Dependencies = make([]AssetAddress, len(adp.Dependencies))
+	}
+	for i, dep := range adp.Dependencies {
+		parsed, err := dep.Parse()
+		if err != nil {
+			// invalid addresses are kept as they are, IsValid will report them
+			ret.Dependencies[i] = AssetAddress(strings.TrimSpace(string(dep)))
+			continue
+		}
+		ret.Dependencies[i] = parsed.Canonical().buildAddress()
+	}
+
+	return ret.aliased()
+}
+
+// canonicalArgument formats numeric and boolean arguments, invalid arguments are returned unchanged.
+func canonicalArgument(schema ArgumentSchema, arg string) string {
+	switch schema.Type {
+	case ARG_INT:
+		if v, err := strconv.ParseInt(arg, 10, 64); err == nil {
+			return strconv.FormatInt(v, 10)
+		}
+	case ARG_FLOAT:
+		if v, err := strconv.ParseFloat(arg, 64); err == nil {
+			return strconv.FormatFloat(v, 'f', -1, 64)
+		}
+	case ARG_BOOL:
+		if v, err := strconv.ParseBool(arg); err == nil {
+			return strconv.FormatBool(v)
+		}
+	}
+	return arg
+}
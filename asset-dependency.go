@@ -0,0 +1,89 @@
+package pcommon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// DependencyConstraint describes what a dependency of an asset must look like.
+type DependencyConstraint struct {
+	// data types accepted for the dependency, any data type if empty
+	DataTypes []DataType `json:"data_types"`
+
+	// asset types accepted for the dependency, any asset type if empty
+	AssetTypes []AssetType `json:"asset_types,omitempty"`
+
+	// columns the dependency must expose
+	Columns []ColumnName `json:"columns"`
+
+	// name of the asset's argument holding a column the dependency must expose
+	ColumnArgument string `json:"column_argument"`
+
+	// if true, the dependency must be an asset parsed from an archive
+	ArchiveOnly bool `json:"archive_only"`
+}
+
+// columnDependency accepts a dependency of any data type exposing the column set in the given argument.
+func columnDependency(columnArgument string) DependencyConstraint {
+	return DependencyConstraint{
+		ColumnArgument: columnArgument,
+	}
+}
+
+// DataType returns the data type of the dependency if only one is accepted, -1 otherwise.
+func (dc DependencyConstraint) DataType() DataType {
+	if len(dc.DataTypes) == 1 {
+		return dc.DataTypes[0]
+	}
+	return -1
+}
+
+// Check returns an error if the dependency does not satisfy the constraint.
+// config and arguments are the ones of the asset depending on it.
+func (dc DependencyConstraint) Check(dep AssetAddressParsed, config AssetStateConfig, arguments []string) error {
+	depConfig, ok := GetAssetConfig(dep.AssetType)
+	if !ok {
+		return fmt.Errorf("asset type %s is not registered", dep.AssetType)
+	}
+
+	if len(dc.DataTypes) > 0 && lo.IndexOf(dc.DataTypes, depConfig.DataType) == -1 {
+		names := lo.Map(dc.DataTypes, func(dt DataType, _ int) string {
+			return dt.String()
+		})
+		return fmt.Errorf("%s data type is %s but must be one of: %s", dep.AssetType, depConfig.DataType.String(), strings.Join(names, ", "))
+	}
+
+	if len(dc.AssetTypes) > 0 && lo.IndexOf(dc.AssetTypes, dep.AssetType) == -1 {
+		names := lo.Map(dc.AssetTypes, func(at AssetType, _ int) string {
+			return string(at)
+		})
+		return fmt.Errorf("%s must be one of: %s", dep.AssetType, strings.Join(names, ", "))
+	}
+
+	if dc.ArchiveOnly && dep.AssetType.GetRequiredArchiveType() == nil {
+		return fmt.Errorf("%s is not an archive asset", dep.AssetType)
+	}
+
+	columns := append([]ColumnName{}, dc.Columns...)
+	if dc.ColumnArgument != "" {
+		idx := lo.IndexOf(lo.Map(config.RequiredArguments, func(arg ArgumentSchema, _ int) string {
+			return arg.Name
+		}), dc.ColumnArgument)
+		if idx == -1 || idx >= len(arguments) {
+			return fmt.Errorf("column argument %s not found", dc.ColumnArgument)
+		}
+		columns = append(columns, ColumnName(arguments[idx]))
+	}
+
+	available := lo.Filter(depConfig.Columns(), func(c ColumnName, _ int) bool {
+		return c != ColumnType.TIME
+	})
+	for _, column := range columns {
+		if lo.IndexOf(available, column) == -1 {
+			return fmt.Errorf("column %s not found in %s (available: %s)", column, dep.AssetType, strings.Join(ColumnNames(available).ToString(), ", "))
+		}
+	}
+	return nil
+}
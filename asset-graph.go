@@ -0,0 +1,188 @@
+package pcommon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// AssetGraph is the dependency graph of a list of assets.
+// Dependencies belonging to another set than their dependent are external: they are not required to be part of the graph.
+type AssetGraph struct {
+	nodes        []AssetAddress
+	dependencies map[AssetAddress][]AssetAddress
+	dependents   map[AssetAddress][]AssetAddress
+	external     map[AssetAddress][]AssetAddress
+}
+
+func NewAssetGraph(addresses []AssetAddress) (*AssetGraph, error) {
+	g := &AssetGraph{
+		nodes:        []AssetAddress{},
+		dependencies: make(map[AssetAddress][]AssetAddress),
+		dependents:   make(map[AssetAddress][]AssetAddress),
+		external:     make(map[AssetAddress][]AssetAddress),
+	}
+
+	for _, address := range addresses {
+		parsed, err := address.Parse()
+		if err != nil {
+			return nil, err
+		}
+		parsed.Dependencies = parsed.Canonical().Dependencies
+		address = parsed.BuildAddress()
+
+		if _, ok := g.dependencies[address]; ok {
+			return nil, fmt.Errorf("duplicate asset address: %s", address)
+		}
+		g.nodes = append(g.nodes, address)
+		g.dependencies[address] = parsed.Dependencies
+
+		for _, dep := range parsed.Dependencies {
+			depParsed, err := dep.Parse()
+			if err != nil {
+				return nil, err
+			}
+			if !sameSetID(depParsed.SetID, parsed.SetID) {
+				g.external[address] = append(g.external[address], dep)
+			}
+		}
+	}
+
+	for _, address := range g.nodes {
+		for _, dep := range g.dependencies[address] {
+			g.dependents[dep] = append(g.dependents[dep], address)
+		}
+	}
+	return g, nil
+}
+
+func (s SetSettings) AssetGraph() (*AssetGraph, error) {
+	addresses := make([]AssetAddress, len(s.Assets))
+	for i, asset := range s.Assets {
+		addresses[i] = asset.Address.AddSetID(s.ID).BuildAddress()
+	}
+	return NewAssetGraph(addresses)
+}
+
+func (g *AssetGraph) Contains(address AssetAddress) bool {
+	_, ok := g.dependencies[address]
+	return ok
+}
+
+func (g *AssetGraph) isExternal(address AssetAddress, dep AssetAddress) bool {
+	return lo.Contains(g.external[address], dep)
+}
+
+// MissingDependencies returns, for each asset, the dependencies of its set that are not part of the graph.
+func (g *AssetGraph) MissingDependencies() map[AssetAddress][]AssetAddress {
+	ret := make(map[AssetAddress][]AssetAddress)
+	for _, address := range g.nodes {
+		for _, dep := range g.dependencies[address] {
+			if !g.Contains(dep) && !g.isExternal(address, dep) {
+				ret[address] = append(ret[address], dep)
+			}
+		}
+	}
+	return ret
+}
+
+// ExternalDependencies returns, for each asset, the dependencies belonging to other sets that are not part of the graph.
+// They have to be provided by the sets they belong to.
+func (g *AssetGraph) ExternalDependencies() map[AssetAddress][]AssetAddress {
+	ret := make(map[AssetAddress][]AssetAddress)
+	for _, address := range g.nodes {
+		for _, dep := range g.external[address] {
+			if !g.Contains(dep) {
+				ret[address] = append(ret[address], dep)
+			}
+		}
+	}
+	return ret
+}
+
+// Check returns an error if a dependency of the same set is missing or if the graph contains a cycle.
+// It is stricter than SetSettings.IsValid, which accepts sets whose assets depend on addresses they do not list.
+func (g *AssetGraph) Check() error {
+	for _, address := range g.nodes {
+		for _, dep := range g.dependencies[address] {
+			if !g.Contains(dep) && !g.isExternal(address, dep) {
+				return fmt.Errorf("missing dependency %s of asset %s", dep, address)
+			}
+		}
+	}
+	_, err := g.BuildOrder()
+	return err
+}
+
+// BuildOrder returns the assets sorted so that every asset comes after its dependencies.
+// Dependencies that are not part of the graph are ignored.
+func (g *AssetGraph) BuildOrder() ([]AssetAddress, error) {
+	remaining := make(map[AssetAddress]int, len(g.nodes))
+	for _, address := range g.nodes {
+		for _, dep := range g.dependencies[address] {
+			if g.Contains(dep) {
+				remaining[address]++
+			}
+		}
+	}
+
+	queue := []AssetAddress{}
+	for _, address := range g.nodes {
+		if remaining[address] == 0 {
+			queue = append(queue, address)
+		}
+	}
+
+	order := make([]AssetAddress, 0, len(g.nodes))
+	for len(queue) > 0 {
+		address := queue[0]
+		queue = queue[1:]
+		order = append(order, address)
+		for _, dependent := range g.dependents[address] {
+			remaining[dependent]--
+			if remaining[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	if len(order) != len(g.nodes) {
+		cycle := []string{}
+		for _, address := range g.nodes {
+			if remaining[address] > 0 {
+				cycle = append(cycle, string(address))
+			}
+		}
+		return nil, fmt.Errorf("dependency cycle between assets: %s", strings.Join(cycle, ", "))
+	}
+	return order, nil
+}
+
+// Dependents returns every asset that has to be rebuilt when the given asset changes, in build order.
+func (g *AssetGraph) Dependents(address AssetAddress) ([]AssetAddress, error) {
+	found := map[AssetAddress]bool{}
+	queue := []AssetAddress{address}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range g.dependents[current] {
+			if !found[dependent] {
+				found[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	order, err := g.BuildOrder()
+	if err != nil {
+		return nil, err
+	}
+	ret := []AssetAddress{}
+	for _, a := range order {
+		if found[a] {
+			ret = append(ret, a)
+		}
+	}
+	return ret, nil
+}
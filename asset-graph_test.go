@@ -0,0 +1,69 @@
+package pcommon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAssetGraph(t *testing.T) {
+	setID := []string{"btc", "usdt"}
+	price := AssetAddressParsed{SetID: setID, AssetType: Asset.SPOT_PRICE}.BuildAddress()
+	volume := AssetAddressParsed{SetID: setID, AssetType: Asset.SPOT_VOLUME}.BuildAddress()
+	sma := AssetAddressParsed{SetID: setID, AssetType: Asset.SMA, Dependencies: []AssetAddress{price}, Arguments: []string{"close", "14"}}.BuildAddress()
+	rsi := AssetAddressParsed{SetID: setID, AssetType: Asset.RSI2, Dependencies: []AssetAddress{sma}, Arguments: []string{"value", "14"}}.BuildAddress()
+	ema := AssetAddressParsed{SetID: setID, AssetType: Asset.EMA, Dependencies: []AssetAddress{volume}, Arguments: []string{"plus", "9"}}.BuildAddress()
+
+	graph, err := NewAssetGraph([]AssetAddress{rsi, ema, sma, volume, price})
+	assert.Nil(t, err)
+	assert.Nil(t, graph.Check())
+
+	order, err := graph.BuildOrder()
+	assert.Nil(t, err)
+	assert.Equal(t, []AssetAddress{volume, price, ema, sma, rsi}, order)
+
+	dependents, err := graph.Dependents(price)
+	assert.Nil(t, err)
+	assert.Equal(t, []AssetAddress{sma, rsi}, dependents)
+
+	graph, err = NewAssetGraph([]AssetAddress{rsi, price})
+	assert.Nil(t, err)
+	assert.NotNil(t, graph.Check())
+	assert.Equal(t, map[AssetAddress][]AssetAddress{rsi: {sma}}, graph.MissingDependencies())
+
+	// the graph check is opt-in: a set missing a dependency stays valid
+	set := SetSettings{
+		ID:       setID,
+		Settings: map[string]int64{"binance": 1},
+		Assets: []AssetSettings{
+			{Address: AssetAddressParsedWithoutSetID{AssetType: Asset.SPOT_PRICE}, MinDataDate: "2020-05-05"},
+			{Address: AssetAddressParsedWithoutSetID{AssetType: Asset.RSI2, Dependencies: []AssetAddress{sma}, Arguments: []string{"value", "14"}}, MinDataDate: "2020-05-05"},
+		},
+	}
+	assert.Nil(t, set.IsValid())
+	graph, err = set.AssetGraph()
+	assert.Nil(t, err)
+	assert.NotNil(t, graph.Check())
+
+	_, err = NewAssetGraph([]AssetAddress{price, price})
+	assert.NotNil(t, err)
+}
+
+func TestAssetGraphCycle(t *testing.T) {
+	// addresses embed their dependencies so a cycle can only come from a hand-built graph
+	graph := &AssetGraph{
+		nodes: []AssetAddress{"a", "b", "c"},
+		dependencies: map[AssetAddress][]AssetAddress{
+			"a": nil,
+			"b": {"a", "c"},
+			"c": {"b"},
+		},
+		dependents: map[AssetAddress][]AssetAddress{
+			"a": {"b"},
+			"b": {"c"},
+			"c": {"b"},
+		},
+	}
+	_, err := graph.BuildOrder()
+	assert.EqualError(t, err, "dependency cycle between assets: b, c")
+}
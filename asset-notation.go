@@ -0,0 +1,250 @@
+package pcommon
+
+import (
+	"fmt"
+	"strings"
+)
+
+/*
+	Human readable notations of an asset address.
+
+	CSV column notation (see BuildCSVColumnName):
+		btcusdt.sma(close|14)[btcusdt.spot_price]
+
+	Pretty notation (see Notation):
+		BTC/USDT.SMA(close, 14)[BTC/USDT.SPOT_PRICE]
+
+	Both are made of an optional set ID, the asset type, an optional argument list between
+	parentheses and an optional dependency list between brackets.
+	A dependency written without set ID belongs to the set of its parent.
+*/
+
+type notationSyntax struct {
+	// separator between arguments and between dependencies
+	separator rune
+	// converts the set ID part of the notation into a set ID
+	parseSetID func(str string) ([]string, error)
+}
+
+// Notation returns an unambiguous human readable form of the address that can be parsed back with ParseAssetNotation.
+// ex: "BTC/USDT.SMA(close, 14)[BTC/USDT.SPOT_PRICE]"
+func (aap AssetAddressParsed) Notation() (string, error) {
+	ret := strings.ToUpper(strings.Join(aap.SetID, "/")) + "." + strings.ToUpper(string(aap.AssetType))
+	if aap.HasArguments() {
+		ret += "(" + strings.Join(aap.Arguments, ", ") + ")"
+	}
+	if aap.HasDependencies() {
+		deps := make([]string, len(aap.Dependencies))
+		for i, depAddr := range aap.Dependencies {
+			dep, err := depAddr.Parse()
+			if err != nil {
+				return "", err
+			}
+			deps[i], err = dep.Notation()
+			if err != nil {
+				return "", err
+			}
+		}
+		ret += "[" + strings.Join(deps, ", ") + "]"
+	}
+	return ret, nil
+}
+
+// ParseAssetNotation parses an address written in the notation returned by Notation.
+func ParseAssetNotation(notation string) (*AssetAddressParsed, error) {
+	syntax := notationSyntax{
+		separator: ',',
+		parseSetID: func(str string) ([]string, error) {
+			return strings.Split(strings.ToLower(str), "/"), nil
+		},
+	}
+
+	address, err := parseNotation(notation, syntax, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := address.IsValid(); err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+// ParseCSVColumnName parses a column name built by BuildCSVColumnName back into an address.
+// Set IDs are joined without separator in CSV column names, so the sets the column can refer to have to be provided:
+// the first one is used for the parts of the column written without set ID.
+func ParseCSVColumnName(column string, sets ...[]string) (*AssetAddressParsed, error) {
+	syntax := notationSyntax{
+		separator: '|',
+		parseSetID: func(str string) ([]string, error) {
+			for _, set := range sets {
+				if SetIDToString(set) == strings.ToLower(str) {
+					return set, nil
+				}
+			}
+			return nil, fmt.Errorf("unknown set id: %s", str)
+		},
+	}
+
+	var defaultSetID []string = nil
+	if len(sets) > 0 {
+		defaultSetID = sets[0]
+	}
+
+	address, err := parseNotation(column, syntax, defaultSetID)
+	if err != nil {
+		return nil, err
+	}
+	if err := address.IsValid(); err != nil {
+		return nil, err
+	}
+	return address, nil
+}
+
+func parseNotation(notation string, syntax notationSyntax, parentSetID []string) (*AssetAddressParsed, error) {
+	head, argumentsStr, dependenciesStr, err := splitNotation(strings.TrimSpace(notation))
+	if err != nil {
+		return nil, err
+	}
+
+	setID := parentSetID
+	assetTypeStr := head
+	if setIDStr, t, found := strings.Cut(head, "."); found {
+		setID, err = syntax.parseSetID(strings.TrimSpace(setIDStr))
+		if err != nil {
+			return nil, err
+		}
+		assetTypeStr = t
+	}
+	if setID == nil {
+		return nil, fmt.Errorf("missing set id in %s", notation)
+	}
+
+	assetTypeStr = strings.ToLower(strings.TrimSpace(assetTypeStr))
+	if assetTypeStr == "" {
+		return nil, fmt.Errorf("missing asset type in %s", notation)
+	}
+
+	var arguments []string = nil
+	if strings.TrimSpace(argumentsStr) != "" {
+		arguments, err = splitNotationList(argumentsStr, syntax.separator)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var dependencies []AssetAddress = nil
+	if strings.TrimSpace(dependenciesStr) != "" {
+		list, err := splitNotationList(dependenciesStr, syntax.separator)
+		if err != nil {
+			return nil, err
+		}
+		for _, depStr := range list {
+			dep, err := parseNotation(depStr, syntax, setID)
+			if err != nil {
+				return nil, err
+			}
+			dependencies = append(dependencies, dep.BuildAddress())
+		}
+	}
+
+	return &AssetAddressParsed{
+		SetID:        setID,
+		AssetType:    AssetType(assetTypeStr),
+		Dependencies: dependencies,
+		Arguments:    arguments,
+	}, nil
+}
+
+// splitNotation splits "head(arguments)[dependencies]" into its three parts.
+func splitNotation(notation string) (head string, arguments string, dependencies string, err error) {
+	headEnd := strings.IndexAny(notation, "()[]")
+	if headEnd == -1 {
+		return notation, "", "", nil
+	}
+	head = notation[:headEnd]
+	rest := notation[headEnd:]
+
+	if strings.HasPrefix(rest, "(") {
+		end, err := findClosingBracket(rest)
+		if err != nil {
+			return "", "", "", err
+		}
+		arguments = rest[1:end]
+		rest = rest[end+1:]
+	}
+
+	if strings.HasPrefix(rest, "[") {
+		end, err := findClosingBracket(rest)
+		if err != nil {
+			return "", "", "", err
+		}
+		dependencies = rest[1:end]
+		rest = rest[end+1:]
+	}
+
+	if strings.TrimSpace(rest) != "" {
+		return "", "", "", fmt.Errorf("unexpected characters: %s", rest)
+	}
+	return head, arguments, dependencies, nil
+}
+
+// findClosingBracket returns the index of the bracket closing the one str starts with.
+func findClosingBracket(str string) (int, error) {
+	level := 0
+	for i, r := range str {
+		switch r {
+		case '(', '[':
+			level++
+		case ')', ']':
+			level--
+			if level < 0 {
+				return -1, fmt.Errorf("unbalanced brackets at position %d", i)
+			}
+			if level == 0 {
+				if (str[0] == '(') != (r == ')') {
+					return -1, fmt.Errorf("mismatched brackets at position %d", i)
+				}
+				return i, nil
+			}
+		}
+	}
+	return -1, fmt.Errorf("unbalanced brackets")
+}
+
+// splitNotationList splits a list on the separators that are not nested in brackets.
+func splitNotationList(list string, separator rune) ([]string, error) {
+	var parts []string
+	var currentPart strings.Builder
+	var bracketLevel int
+
+	for i, r := range list {
+		switch r {
+		case separator:
+			if bracketLevel == 0 {
+				parts = append(parts, strings.TrimSpace(currentPart.String()))
+				currentPart.Reset()
+				continue
+			}
+		case '(', '[':
+			bracketLevel++
+		case ')', ']':
+			bracketLevel--
+			if bracketLevel < 0 {
+				return nil, fmt.Errorf("unbalanced brackets at position %d", i)
+			}
+		}
+		currentPart.WriteRune(r)
+	}
+
+	if bracketLevel != 0 {
+		return nil, fmt.Errorf("unbalanced brackets")
+	}
+
+	parts = append(parts, strings.TrimSpace(currentPart.String()))
+	for _, part := range parts {
+		if part == "" {
+			return nil, fmt.Errorf("empty element in list: %s", list)
+		}
+	}
+	return parts, nil
+}
@@ -0,0 +1,176 @@
+package pcommon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/samber/lo"
+)
+
+/*
+	Every asset type known by the library is registered here:
+	validation, the ressources sent to clients and the indicator builder all read from the registry.
+
+	Registration is not thread safe and is meant to be done from init functions,
+	before any asset is validated or computed.
+*/
+
+type registeredAsset struct {
+	config    AssetStateConfig
+	indicator IndicatorFactory
+}
+
+var assetRegistry = map[AssetType]registeredAsset{}
+
+func init() {
+	for _, config := range DEFAULT_ASSETS {
+		if err := RegisterAsset(config, defaultIndicators[config.ID]); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// RegisterAsset adds an asset type to the registry.
+// indicator must be nil for assets parsed from archives, and set for indicators (assets with dependencies).
+func RegisterAsset(config AssetStateConfig, indicator IndicatorFactory) error {
+	if config.ID == "" {
+		return errors.New("asset type is empty")
+	}
+	if _, ok := assetRegistry[config.ID]; ok {
+		return fmt.Errorf("asset type %s is already registered", config.ID)
+	}
+	if lo.IndexOf([]DataType{UNIT, QUANTITY, POINT, VECTOR}, config.DataType) == -1 {
+		return fmt.Errorf("asset type %s has an invalid data type", config.ID)
+	}
+	if err := checkOutputColumns(config); err != nil {
+		return err
+	}
+	if config.SetUpDecimals == nil {
+		return fmt.Errorf("config decimals not set for asset type %s", config.ID)
+	}
+	if len(config.RequiredDependencies) == 0 && indicator != nil {
+		return fmt.Errorf("asset type %s has an indicator but no dependencies", config.ID)
+	}
+	if len(config.RequiredDependencies) > 0 && indicator == nil {
+		return fmt.Errorf("asset type %s has dependencies but no indicator", config.ID)
+	}
+
+	if indicator != nil {
+		if err := checkIndicatorOutput(config, indicator()); err != nil {
+			return err
+		}
+	}
+
+	assetRegistry[config.ID] = registeredAsset{config: config, indicator: indicator}
+	return nil
+}
+
+// checkOutputColumns returns an error if a vector asset has no output columns, or if another asset has some.
+func checkOutputColumns(config AssetStateConfig) error {
+	if config.DataType != VECTOR {
+		if len(config.OutputColumns) > 0 {
+			return fmt.Errorf("asset type %s has output columns but is not a vector", config.ID)
+		}
+		return nil
+	}
+	if len(config.OutputColumns) == 0 {
+		return fmt.Errorf("vector asset type %s has no output columns", config.ID)
+	}
+	if len(lo.Uniq(config.OutputColumns)) != len(config.OutputColumns) {
+		return fmt.Errorf("vector asset type %s has duplicate output columns", config.ID)
+	}
+	if lo.Contains(config.OutputColumns, ColumnType.TIME) || lo.Contains(config.OutputColumns, "") {
+		return fmt.Errorf("vector asset type %s has an invalid output column", config.ID)
+	}
+	return nil
+}
+
+// checkIndicatorOutput returns an error if the indicator does not compute the data type of the asset.
+func checkIndicatorOutput(config AssetStateConfig, indicator Indicator) error {
+	if config.VariadicDependencies {
+		if _, ok := indicator.(ArgumentDependenciesIndicator); !ok {
+			return fmt.Errorf("indicator of asset type %s must set its dependencies from its arguments", config.ID)
+		}
+	}
+	if config.DataType == VECTOR {
+		if len(config.RequiredDependencies) > 1 || config.VariadicDependencies {
+			return fmt.Errorf("vector asset type %s cannot have several dependencies", config.ID)
+		}
+		if _, ok := indicator.(VectorIndicator); !ok {
+			return fmt.Errorf("indicator of asset type %s must compute vectors", config.ID)
+		}
+		return nil
+	}
+	if len(config.RequiredDependencies) > 1 || config.VariadicDependencies {
+		if _, ok := indicator.(MultiPointIndicator); !ok {
+			return fmt.Errorf("indicator of asset type %s must compute points from several dependencies", config.ID)
+		}
+		return nil
+	}
+	if _, ok := indicator.(PointIndicator); !ok {
+		return fmt.Errorf("indicator of asset type %s must compute points", config.ID)
+	}
+	return nil
+}
+
+// RegisterArchive adds an archive type and the tree of the assets it contains.
+// The assets of the tree must be registered first.
+func RegisterArchive(archiveType ArchiveType, tree *ArchiveDataTree) error {
+	if _, ok := ArchivesIndex[archiveType]; ok {
+		return fmt.Errorf("archive type %s is already registered", archiveType)
+	}
+	for _, branch := range tree.Columns {
+		config, ok := GetAssetConfig(branch.Asset)
+		if !ok {
+			return fmt.Errorf("asset type %s of archive %s is not registered", branch.Asset, archiveType)
+		}
+		if len(config.RequiredDependencies) > 0 {
+			return fmt.Errorf("asset type %s of archive %s cannot have dependencies", branch.Asset, archiveType)
+		}
+		if len(config.RequiredArguments) != len(branch.Arguments) {
+			return fmt.Errorf("asset type %s of archive %s: invalid number of arguments", branch.Asset, archiveType)
+		}
+		for i, arg := range branch.Arguments {
+			if err := config.RequiredArguments[i].Validate(arg); err != nil {
+				return fmt.Errorf("asset type %s of archive %s: %s", branch.Asset, archiveType, err)
+			}
+		}
+	}
+	if tree.ParametricBranch != nil {
+		config, ok := GetAssetConfig(tree.ParametricAsset)
+		if !ok {
+			return fmt.Errorf("asset type %s of archive %s is not registered", tree.ParametricAsset, archiveType)
+		}
+		if len(config.RequiredDependencies) > 0 || len(config.RequiredArguments) == 0 {
+			return fmt.Errorf("parametric asset type %s of archive %s must have arguments and no dependencies", tree.ParametricAsset, archiveType)
+		}
+	}
+
+	ArchivesIndex[archiveType] = tree
+	ARCHIVE_TYPE_LIST = append(ARCHIVE_TYPE_LIST, archiveType)
+	return nil
+}
+
+func GetAssetConfig(assetType AssetType) (AssetStateConfig, bool) {
+	asset, ok := assetRegistry[assetType]
+	return asset.config, ok
+}
+
+func IsAssetTypeRegistered(assetType AssetType) bool {
+	_, ok := assetRegistry[assetType]
+	return ok
+}
+
+// RegisteredAssets returns the config of every registered asset type.
+func RegisteredAssets() AvailableAssets {
+	ret := make(AvailableAssets, len(assetRegistry))
+	for assetType, asset := range assetRegistry {
+		ret[assetType] = asset.config
+	}
+	return ret
+}
+
+func getIndicatorFactory(assetType AssetType) IndicatorFactory {
+	return assetRegistry[assetType].indicator
+}
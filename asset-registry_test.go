@@ -0,0 +1,121 @@
+package pcommon
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type doubleIndicator struct {
+	column ColumnName
+}
+
+func (ind *doubleIndicator) Init(args IndicatorArguments) error {
+	ind.column = args.Column("column")
+	return nil
+}
+
+func (ind *doubleIndicator) Update(data Data) (Point, bool) {
+	return Point{columnValue(data, ind.column) * 2}, true
+}
+
+func (ind *doubleIndicator) MarshalState() ([]byte, error)     { return nil, nil }
+func (ind *doubleIndicator) UnmarshalState(state []byte) error { return nil }
+func (ind *doubleIndicator) WarmupPeriod() int                 { return 1 }
+
+// rangeIndicator outputs the low and the high of a unit
+type rangeIndicator struct{}
+
+func (ind *rangeIndicator) Init(args IndicatorArguments) error { return nil }
+func (ind *rangeIndicator) MarshalState() ([]byte, error)      { return nil, nil }
+func (ind *rangeIndicator) UnmarshalState(state []byte) error  { return nil }
+func (ind *rangeIndicator) WarmupPeriod() int                  { return 1 }
+func (ind *rangeIndicator) UpdateVector(data Data) ([]float64, bool) {
+	return []float64{data.Min(), data.Max()}, true
+}
+
+func TestRegisterAsset(t *testing.T) {
+	const DOUBLE AssetType = "test_double"
+
+	config := AssetStateConfig{
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 2
+		},
+		ID:                   DOUBLE,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE)},
+		Label:                "Double",
+	}
+
+	factory := func() Indicator { return &doubleIndicator{} }
+	assert.NotNil(t, RegisterAsset(config, nil), "indicators need a factory")
+	assert.NotNil(t, RegisterAsset(DEFAULT_ASSETS[Asset.SMA], factory), "asset types are registered once")
+	assert.Nil(t, RegisterAsset(config, factory))
+
+	_, ok := RegisteredAssets()[DOUBLE]
+	assert.True(t, ok)
+
+	price := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.SPOT_PRICE}
+	double := AssetAddressParsed{
+		SetID:        []string{"btc", "usdt"},
+		AssetType:    DOUBLE,
+		Dependencies: []AssetAddress{price.BuildAddress()},
+		Arguments:    []string{"high"},
+	}
+	assert.Nil(t, double.IsValid())
+
+	b := NewIndicatorDataBuilder(DOUBLE, nil, []string{"high"}, -1)
+	p, err := b.ComputeUnsafe(NewUnit(21).ToTime(NewTimeUnit(1587607201)))
+	assert.Nil(t, err)
+	assert.Equal(t, 42.0, p.Value)
+
+	_, err = b.ComputeUnsafe(NewQuantity(21).ToTime(NewTimeUnit(1587607201)))
+	assert.EqualError(t, err, "column high not found")
+}
+
+func TestRegisterVectorAsset(t *testing.T) {
+	const RANGE AssetType = "test_range"
+
+	config := AssetStateConfig{
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 2
+		},
+		ID:                   RANGE,
+		DataType:             VECTOR,
+		RequiredDependencies: []DependencyConstraint{{DataTypes: []DataType{UNIT}}},
+		Label:                "Range",
+	}
+
+	factory := func() Indicator { return &rangeIndicator{} }
+	assert.NotNil(t, RegisterAsset(config, factory), "vectors need output columns")
+	config.OutputColumns = []ColumnName{ColumnType.LOW, ColumnType.HIGH}
+	assert.NotNil(t, RegisterAsset(config, func() Indicator { return &doubleIndicator{} }), "vector indicators compute vectors")
+	assert.Nil(t, RegisterAsset(config, factory))
+
+	registered, _ := GetAssetConfig(RANGE)
+	assert.Equal(t, []ColumnName{ColumnType.TIME, ColumnType.LOW, ColumnType.HIGH}, registered.Columns())
+	assert.Equal(t, []string{"range.high"}, registered.Header("range", CSVCheckListRequirement{ColumnType.HIGH: true}))
+
+	unit := Unit{Open: 10, High: 12.345, Low: 9.5, Close: 11, Count: 2}.ToTime(NewTimeUnit(1587607201))
+	b := NewIndicatorDataBuilder(RANGE, nil, nil, 2)
+	v, err := b.ComputeVectorUnsafe(unit)
+	assert.Nil(t, err)
+	assert.Equal(t, []float64{9.5, 12.35}, v.Values)
+	_, err = b.ComputeUnsafe(unit)
+	assert.NotNil(t, err, "vector assets have no point")
+
+	data, err := ParseAssetData(RANGE, v.ToRaw(2), unit.Time)
+	assert.Nil(t, err)
+	high, _ := data.ValueAt(ColumnType.HIGH)
+	assert.Equal(t, 12.35, high)
+
+	// a vector column can be the column of another indicator
+	rangeAddr := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: RANGE, Dependencies: []AssetAddress{
+		AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.SPOT_PRICE}.BuildAddress(),
+	}}
+	sma := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.SMA, Dependencies: []AssetAddress{rangeAddr.BuildAddress()}, Arguments: []string{"high", "2"}}
+	assert.Nil(t, sma.IsValid())
+	sma.Arguments = []string{"close", "2"}
+	assert.NotNil(t, sma.IsValid())
+}
@@ -29,7 +29,7 @@ func (as AssetSettings) IsValid(setSettings SetSettings) error {
 		return err
 	}
 
-	config := DEFAULT_ASSETS[as.Address.AssetType]
+	config, _ := GetAssetConfig(as.Address.AssetType)
 	if config.SetUpDecimals == nil {
 		return fmt.Errorf("config decimals not set for asset type %s", as.Address.AssetType)
 	}
@@ -1,13 +1,5 @@
 package pcommon
 
-import (
-	"errors"
-	"log"
-	"reflect"
-	"strconv"
-	"strings"
-)
-
 type AssetType string
 type AllAssetTypes struct {
 	SPOT_PRICE  AssetType
@@ -16,6 +8,8 @@ type AllAssetTypes struct {
 	FUTURES_PRICE  AssetType
 	FUTURES_VOLUME AssetType
 
+	// parametric book depth (see asset-book-depth.go), BOOK_DEPTH_P1 .. BOOK_DEPTH_M5 being aliases of it
+	BOOK_DEPTH    AssetType
 	BOOK_DEPTH_P1 AssetType
 	BOOK_DEPTH_P2 AssetType
 	BOOK_DEPTH_P3 AssetType
@@ -48,6 +42,52 @@ type AllAssetTypes struct {
 	WMA AssetType
 	HMA AssetType
 
+	DEMA  AssetType
+	TEMA  AssetType
+	KAMA  AssetType
+	ZLEMA AssetType
+	VWMA  AssetType
+
+	MACD AssetType
+
+	//Volatility
+	STDDEV    AssetType
+	BOLLINGER AssetType
+	TR        AssetType
+	ATR       AssetType
+
+	//Oscillators on the whole candle
+	STOCHASTIC AssetType
+	WILLIAMS_R AssetType
+	CCI        AssetType
+
+	//Rolling statistics
+	ZSCORE          AssetType
+	PERCENTILE_RANK AssetType
+	ROLLING_MIN     AssetType
+	ROLLING_MAX     AssetType
+	ROLLING_SUM     AssetType
+
+	//Between two assets, possibly of different sets
+	CORRELATION AssetType
+	COVARIANCE  AssetType
+	BETA        AssetType
+
+	//Arithmetic expression over several assets
+	FORMULA AssetType
+
+	//Liquidity from the book depth
+	BOOK_IMBALANCE        AssetType
+	BOOK_CUMULATIVE_DEPTH AssetType
+	BOOK_SLOPE            AssetType
+	BOOK_DEPTH_CHANGE     AssetType
+
+	//Volume and price
+	OBV AssetType
+	AD  AssetType
+	MFI AssetType
+	CMF AssetType
+
 	WA AssetType
 }
 
@@ -58,6 +98,7 @@ var Asset = AllAssetTypes{
 	FUTURES_PRICE:  "futures_price",
 	FUTURES_VOLUME: "futures_volume",
 
+	BOOK_DEPTH:    "book_depth",
 	BOOK_DEPTH_P1: "bd-p1",
 	BOOK_DEPTH_P2: "bd-p2",
 	BOOK_DEPTH_P3: "bd-p3",
@@ -85,41 +126,46 @@ var Asset = AllAssetTypes{
 	WMA:  "wma",
 	HMA:  "hma",
 
-	WA: "wa",
-}
+	DEMA:  "dema",
+	TEMA:  "tema",
+	KAMA:  "kama",
+	ZLEMA: "zlema",
+	VWMA:  "vwma",
 
-var AssetTypeMap = Asset.ToMap()
+	MACD: "macd",
 
-func (a AllAssetTypes) ToMap() map[string]bool {
-	v := reflect.ValueOf(Asset)
-	if v.Kind() != reflect.Struct {
-		log.Fatal("expected a struct")
-	}
-	m := make(map[string]bool)
-	for i := 0; i < v.NumField(); i++ {
-		m[v.Field(i).String()] = true
-	}
-	return m
-}
+	STDDEV:    "stddev",
+	BOLLINGER: "bollinger",
+	TR:        "tr",
+	ATR:       "atr",
 
-func (asset AssetType) GetBookDepthAssetPercentage() (int, error) {
-	if asset == Asset.BOOK_DEPTH_M1 || asset == Asset.BOOK_DEPTH_M2 || asset == Asset.BOOK_DEPTH_M3 || asset == Asset.BOOK_DEPTH_M4 || asset == Asset.BOOK_DEPTH_M5 ||
-		asset == Asset.BOOK_DEPTH_P1 || asset == Asset.BOOK_DEPTH_P2 || asset == Asset.BOOK_DEPTH_P3 || asset == Asset.BOOK_DEPTH_P4 || asset == Asset.BOOK_DEPTH_P5 {
-		lastChar := asset[len(asset)-1:]
-		percent, err := strconv.Atoi(string(lastChar))
-		if err != nil {
-			return 0, err
-		}
+	STOCHASTIC: "stoch",
+	WILLIAMS_R: "willr",
+	CCI:        "cci",
 
-		isPlus := strings.HasPrefix(string(asset), "bd-p")
-		isMinus := strings.HasPrefix(string(asset), "bd-m")
-		if isPlus {
-			return percent, nil
-		} else if isMinus {
-			return -percent, nil
-		}
-	}
-	return 0, errors.New("invalid asset")
+	ZSCORE:          "zscore",
+	PERCENTILE_RANK: "percentile_rank",
+	ROLLING_MIN:     "rolling_min",
+	ROLLING_MAX:     "rolling_max",
+	ROLLING_SUM:     "rolling_sum",
+
+	CORRELATION: "correlation",
+	COVARIANCE:  "covariance",
+	BETA:        "beta",
+
+	FORMULA: "formula",
+
+	BOOK_IMBALANCE:        "bd_imbalance",
+	BOOK_CUMULATIVE_DEPTH: "bd_cumulative",
+	BOOK_SLOPE:            "bd_slope",
+	BOOK_DEPTH_CHANGE:     "bd_change",
+
+	OBV: "obv",
+	AD:  "ad",
+	MFI: "mfi",
+	CMF: "cmf",
+
+	WA: "wa",
 }
 
 type AssetStateConfig struct {
@@ -128,16 +174,35 @@ type AssetStateConfig struct {
 	ID       AssetType
 	DataType DataType
 
-	RequiredDependencyDataTypes []DataType
-	RequiredArgumentTypes       []reflect.Type
+	RequiredDependencies []DependencyConstraint
+	RequiredArguments    []ArgumentSchema
 
 	/*
 		only for assets with dependencies,
 		if true the asset won't be indexed directly on its dependency's timeframe's data
-		but instead will recalculate its data for every timeframe from the data of the minimum timeframe of its dependencies.
+		but instead will recalculate its data for every timeframe from the data of the minimum timeframe of its dependencies
+		(see MultiTimeframeBuilder).
 	*/
 	IndexedOnMinTimeframe bool
 
+	// only for VECTOR assets, the names of the values computed
+	OutputColumns []ColumnName
+
+	/*
+		if true, the dependencies after the first one may belong to other sets (ex: the beta of an altcoin to BTC),
+		the first dependency always belongs to the set of the asset.
+	*/
+	AllowCrossSetDependencies bool
+
+	/*
+		if true, the asset takes at least as many dependencies as RequiredDependencies, the last constraint applying to the extra ones.
+		The indicator sets the number of dependencies from the arguments (see ArgumentDependenciesIndicator).
+	*/
+	VariadicDependencies bool
+
+	// if set, checks the dependencies against each other once each one is valid (ex: the two sides of the same book depth band)
+	CheckDependencies func(dependencies []AssetAddressParsed) error
+
 	Label       string
 	Description string
 	Color       string
@@ -145,236 +210,709 @@ type AssetStateConfig struct {
 
 type AvailableAssets map[AssetType]AssetStateConfig
 
+// Columns returns the columns of the asset's data: the ones of its data type, or its output columns for vectors.
+func (c AssetStateConfig) Columns() []ColumnName {
+	if c.DataType == VECTOR {
+		return append([]ColumnName{ColumnType.TIME}, c.OutputColumns...)
+	}
+	return c.DataType.Columns()
+}
+
+// dependencyConstraint returns the constraint of the i-th dependency of the asset.
+func (c AssetStateConfig) dependencyConstraint(i int) DependencyConstraint {
+	if c.VariadicDependencies && i >= len(c.RequiredDependencies) {
+		return c.RequiredDependencies[len(c.RequiredDependencies)-1]
+	}
+	return c.RequiredDependencies[i]
+}
+
+func (c AssetStateConfig) Header(prefix string, requirement CSVCheckListRequirement) []string {
+	return columnsHeader(c.Columns(), prefix, requirement)
+}
+
+// indicators computed by the library, registered along with DEFAULT_ASSETS
+var defaultIndicators = map[AssetType]IndicatorFactory{
+	Asset.RSI:  func() Indicator { return &rsiIndicator{} },
+	Asset.RSI2: func() Indicator { return &rsiIndicator{} },
+	Asset.SMA:  func() Indicator { return &smaIndicator{} },
+	Asset.EMA:  func() Indicator { return &emaIndicator{} },
+	Asset.WMA:  func() Indicator { return &wmaIndicator{} },
+	Asset.HMA:  func() Indicator { return &hmaIndicator{} },
+
+	Asset.DEMA:  func() Indicator { return &demaIndicator{} },
+	Asset.TEMA:  func() Indicator { return &temaIndicator{} },
+	Asset.KAMA:  func() Indicator { return &kamaIndicator{} },
+	Asset.ZLEMA: func() Indicator { return &zlemaIndicator{} },
+	Asset.VWMA:  func() Indicator { return &vwmaIndicator{} },
+
+	Asset.MACD: func() Indicator { return &macdIndicator{} },
+
+	Asset.STDDEV:    func() Indicator { return &stddevIndicator{} },
+	Asset.BOLLINGER: func() Indicator { return &bollingerIndicator{} },
+	Asset.TR:        func() Indicator { return &trIndicator{} },
+	Asset.ATR:       func() Indicator { return &atrIndicator{} },
+
+	Asset.STOCHASTIC: func() Indicator { return &stochasticIndicator{} },
+	Asset.WILLIAMS_R: func() Indicator { return &williamsRIndicator{} },
+	Asset.CCI:        func() Indicator { return &cciIndicator{} },
+
+	Asset.ZSCORE:          func() Indicator { return &zscoreIndicator{} },
+	Asset.PERCENTILE_RANK: func() Indicator { return &percentileRankIndicator{} },
+	Asset.ROLLING_MIN:     func() Indicator { return &rollingExtremumIndicator{max: false} },
+	Asset.ROLLING_MAX:     func() Indicator { return &rollingExtremumIndicator{max: true} },
+	Asset.ROLLING_SUM:     func() Indicator { return &rollingSumIndicator{} },
+
+	Asset.CORRELATION: func() Indicator { return &pairIndicator{statistic: pairCorrelation} },
+	Asset.COVARIANCE:  func() Indicator { return &pairIndicator{statistic: pairCovariance} },
+	Asset.BETA:        func() Indicator { return &pairIndicator{statistic: pairBeta} },
+
+	Asset.FORMULA: func() Indicator { return &formulaIndicator{} },
+
+	Asset.BOOK_IMBALANCE:        func() Indicator { return &bookImbalanceIndicator{} },
+	Asset.BOOK_CUMULATIVE_DEPTH: func() Indicator { return &bookCumulativeDepthIndicator{} },
+	Asset.BOOK_SLOPE:            func() Indicator { return &bookSlopeIndicator{} },
+	Asset.BOOK_DEPTH_CHANGE:     func() Indicator { return &bookChangeIndicator{} },
+
+	Asset.OBV: func() Indicator { return &obvIndicator{} },
+	Asset.AD:  func() Indicator { return &adIndicator{} },
+	Asset.MFI: func() Indicator { return &mfiIndicator{} },
+	Asset.CMF: func() Indicator { return &cmfIndicator{} },
+
+	Asset.WA: func() Indicator { return &vwapIndicator{} },
+}
+
+// assets provided by the library, registered at init (see RegisterAsset)
+
 var DEFAULT_ASSETS = AvailableAssets{
 	// Binance spot trades
 	Asset.SPOT_PRICE: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return priceDecimals(priceUSDA / priceUSDB)
 		},
-		Asset.SPOT_PRICE, UNIT, nil, nil, false,
-		"Spot Price", "The current price at which an asset is bought or sold in the spot market on Binance.",
-		"#5F9EA0",
+		ID:          Asset.SPOT_PRICE,
+		DataType:    UNIT,
+		Label:       "Spot Price",
+		Description: "The current price at which an asset is bought or sold in the spot market on Binance.",
+		Color:       "#5F9EA0",
 	},
 	Asset.SPOT_VOLUME: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return countDivisionsTo(priceUSDA/priceUSDB, 0.01)
 		},
-		Asset.SPOT_VOLUME, QUANTITY, nil, nil, false,
-		"Spot Volume", "The total amount of an asset traded in the spot market on Binance.",
-		"#228B22",
+		ID:          Asset.SPOT_VOLUME,
+		DataType:    QUANTITY,
+		Label:       "Spot Volume",
+		Description: "The total amount of an asset traded in the spot market on Binance.",
+		Color:       "#228B22",
 	},
 
 	// Binance order book depth
-	Asset.BOOK_DEPTH_P1: {
-		func(priceUSDA, priceUSDB float64) int8 {
-			return countDivisionsTo(priceUSDA/priceUSDB, 10)
-		},
-		Asset.BOOK_DEPTH_P1, UNIT, nil, nil, false,
-		"Liquidity +1% Price", "Available liquidity at a price level 1% above the current market price on Binance.",
-		"#044f56",
-	},
-	Asset.BOOK_DEPTH_P2: {
-		func(priceUSDA, priceUSDB float64) int8 {
-			return countDivisionsTo(priceUSDA/priceUSDB, 10)
-		},
-		Asset.BOOK_DEPTH_P2, UNIT, nil, nil, false,
-		"Liquidity +2% Price", "Available liquidity at a price level 2% above the current market price on Binance.",
-		"#07636c",
-	},
-	Asset.BOOK_DEPTH_P3: {
-		func(priceUSDA, priceUSDB float64) int8 {
-			return countDivisionsTo(priceUSDA/priceUSDB, 10)
+	Asset.BOOK_DEPTH: {
+		SetUpDecimals: bookDepthDecimals,
+		ID:            Asset.BOOK_DEPTH,
+		DataType:      UNIT,
+		RequiredArguments: []ArgumentSchema{
+			{
+				Name:        "percentage",
+				Type:        ARG_FLOAT,
+				Default:     "1",
+				Min:         argumentBound(-100),
+				Max:         argumentBound(100),
+				NonZero:     true,
+				Description: "Distance to the market price in percent, negative for the bid side and positive for the ask side.",
+			},
 		},
-		Asset.BOOK_DEPTH_P3, UNIT, nil, nil, false,
-		"Liquidity +3% Price", "Available liquidity at a price level 3% above the current market price on Binance.",
-		"#0a7882",
-	},
-	Asset.BOOK_DEPTH_P4: {
-		func(priceUSDA, priceUSDB float64) int8 {
-			return countDivisionsTo(priceUSDA/priceUSDB, 10)
-		},
-		Asset.BOOK_DEPTH_P4, UNIT, nil, nil, false,
-		"Liquidity +4% Price", "Available liquidity at a price level 4% above the current market price on Binance.",
-		"#0e8d99",
-	},
-	Asset.BOOK_DEPTH_P5: {
-		func(priceUSDA, priceUSDB float64) int8 {
-			return countDivisionsTo(priceUSDA/priceUSDB, 10)
-		},
-		Asset.BOOK_DEPTH_P5, UNIT, nil, nil, false,
-		"Liquidity +5% Price", "Available liquidity at a price level 5% above the current market price on Binance.",
-		"#12a3b0",
-	},
-	Asset.BOOK_DEPTH_M1: {
-		func(priceUSDA, priceUSDB float64) int8 {
-			return countDivisionsTo(priceUSDA/priceUSDB, 10)
-		},
-		Asset.BOOK_DEPTH_M1, UNIT, nil, nil, false,
-		"Liquidity -1% Price", "Available liquidity at a price level 1% below the current market price on Binance.",
-		"#0b186b",
-	},
-	Asset.BOOK_DEPTH_M2: {
-		func(priceUSDA, priceUSDB float64) int8 {
-			return countDivisionsTo(priceUSDA/priceUSDB, 10)
-		},
-		Asset.BOOK_DEPTH_M2, UNIT, nil, nil, false,
-		"Liquidity -2% Price", "Available liquidity at a price level 2% below the current market price on Binance.",
-		"#0b186b",
-	},
-	Asset.BOOK_DEPTH_M3: {
-		func(priceUSDA, priceUSDB float64) int8 {
-			return countDivisionsTo(priceUSDA/priceUSDB, 10)
-		},
-		Asset.BOOK_DEPTH_M3, UNIT, nil, nil, false,
-		"Liquidity -3% Price", "Available liquidity at a price level 3% below the current market price on Binance.",
-		"#08135c",
-	},
-	Asset.BOOK_DEPTH_M4: {
-		func(priceUSDA, priceUSDB float64) int8 {
-			return countDivisionsTo(priceUSDA/priceUSDB, 10)
-		},
-		Asset.BOOK_DEPTH_M4, UNIT, nil, nil, false,
-		"Liquidity -4% Price", "Available liquidity at a price level 4% below the current market price on Binance.",
-		"#060f4e",
-	},
-	Asset.BOOK_DEPTH_M5: {
-		func(priceUSDA, priceUSDB float64) int8 {
-			return countDivisionsTo(priceUSDA/priceUSDB, 10)
-		},
-		Asset.BOOK_DEPTH_M5, UNIT, nil, nil, false,
-		"Liquidity -5% Price", "Available liquidity at a price level 5% below the current market price on Binance.",
-		"#040a3f",
+		Label:       "Liquidity",
+		Description: "Available liquidity within a percentage of the current market price on Binance.",
+		Color:       "#0a7882",
 	},
+	Asset.BOOK_DEPTH_P1: bookDepthAliasConfig(Asset.BOOK_DEPTH_P1, "#044f56"),
+	Asset.BOOK_DEPTH_P2: bookDepthAliasConfig(Asset.BOOK_DEPTH_P2, "#07636c"),
+	Asset.BOOK_DEPTH_P3: bookDepthAliasConfig(Asset.BOOK_DEPTH_P3, "#0a7882"),
+	Asset.BOOK_DEPTH_P4: bookDepthAliasConfig(Asset.BOOK_DEPTH_P4, "#0e8d99"),
+	Asset.BOOK_DEPTH_P5: bookDepthAliasConfig(Asset.BOOK_DEPTH_P5, "#12a3b0"),
+	Asset.BOOK_DEPTH_M1: bookDepthAliasConfig(Asset.BOOK_DEPTH_M1, "#0b186b"),
+	Asset.BOOK_DEPTH_M2: bookDepthAliasConfig(Asset.BOOK_DEPTH_M2, "#0b186b"),
+	Asset.BOOK_DEPTH_M3: bookDepthAliasConfig(Asset.BOOK_DEPTH_M3, "#08135c"),
+	Asset.BOOK_DEPTH_M4: bookDepthAliasConfig(Asset.BOOK_DEPTH_M4, "#060f4e"),
+	Asset.BOOK_DEPTH_M5: bookDepthAliasConfig(Asset.BOOK_DEPTH_M5, "#040a3f"),
 
 	// Metrics
 	Asset.METRIC_SUM_OPEN_INTEREST: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return countDivisionsTo(priceUSDA/priceUSDB, 10)
 		},
-		Asset.METRIC_SUM_OPEN_INTEREST, UNIT, nil, nil, false,
-		"Open Interest", "The total number of outstanding derivative contracts, such as options or futures, that have not been settled on Binance.",
-		"#f1ae8a",
+		ID:          Asset.METRIC_SUM_OPEN_INTEREST,
+		DataType:    UNIT,
+		Label:       "Open Interest",
+		Description: "The total number of outstanding derivative contracts, such as options or futures, that have not been settled on Binance.",
+		Color:       "#f1ae8a",
 	},
 
 	Asset.METRIC_COUNT_TOP_TRADER_LONG_SHORT_RATIO: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return 4
 		},
-		Asset.METRIC_COUNT_TOP_TRADER_LONG_SHORT_RATIO, UNIT, nil, nil, false,
-		"Taker Long/Short Ratio", "The ratio of long to short positions taken by top traders on Binance.",
-		"#5e4e29",
+		ID:          Asset.METRIC_COUNT_TOP_TRADER_LONG_SHORT_RATIO,
+		DataType:    UNIT,
+		Label:       "Taker Long/Short Ratio",
+		Description: "The ratio of long to short positions taken by top traders on Binance.",
+		Color:       "#5e4e29",
 	},
 	Asset.METRIC_SUM_TOP_TRADER_LONG_SHORT_RATIO: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return 4
 		},
-		Asset.METRIC_SUM_TOP_TRADER_LONG_SHORT_RATIO, UNIT, nil, nil, false,
-		"Top Trader Long/Short Ratio", "The ratio of the sum of long to short positions taken by top traders on Binance.",
-		"#6d5e3d",
+		ID:          Asset.METRIC_SUM_TOP_TRADER_LONG_SHORT_RATIO,
+		DataType:    UNIT,
+		Label:       "Top Trader Long/Short Ratio",
+		Description: "The ratio of the sum of long to short positions taken by top traders on Binance.",
+		Color:       "#6d5e3d",
 	},
 	Asset.METRIC_COUNT_LONG_SHORT_RATIO: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return 4
 		},
-		Asset.METRIC_COUNT_LONG_SHORT_RATIO, UNIT, nil, nil, false,
-		"Long/Short Ratio", "The overall ratio of long to short positions taken by all traders on Binance.",
-		"#b09763",
+		ID:          Asset.METRIC_COUNT_LONG_SHORT_RATIO,
+		DataType:    UNIT,
+		Label:       "Long/Short Ratio",
+		Description: "The overall ratio of long to short positions taken by all traders on Binance.",
+		Color:       "#b09763",
 	},
 	Asset.METRIC_SUM_TAKER_LONG_SHORT_VOL_RATIO: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return 4
 		},
-		Asset.METRIC_SUM_TAKER_LONG_SHORT_VOL_RATIO, UNIT, nil, nil, false,
-		"Taker Long/Short Volume Ratio", "The ratio of long to short volumes taken by top traders on Binance.",
-		"#b8a173",
+		ID:          Asset.METRIC_SUM_TAKER_LONG_SHORT_VOL_RATIO,
+		DataType:    UNIT,
+		Label:       "Taker Long/Short Volume Ratio",
+		Description: "The ratio of long to short volumes taken by top traders on Binance.",
+		Color:       "#b8a173",
 	},
 
 	// Supply
 	Asset.CIRCULATING_SUPPLY: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return 0
 		},
-		Asset.CIRCULATING_SUPPLY, UNIT, nil, nil, false,
-		"Circulating Supply", "The total number of tokens that are currently available in circulation.",
-		"#eaeaea",
+		ID:          Asset.CIRCULATING_SUPPLY,
+		DataType:    UNIT,
+		Label:       "Circulating Supply",
+		Description: "The total number of tokens that are currently available in circulation.",
+		Color:       "#eaeaea",
 	},
 
 	// Binance futures
 	Asset.FUTURES_PRICE: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return priceDecimals(priceUSDA / priceUSDB)
 		},
-		Asset.FUTURES_PRICE, UNIT, nil, nil, false,
-		"Futures Price", "The current price at which a futures contract is trading on Binance.",
-		"#386061",
+		ID:          Asset.FUTURES_PRICE,
+		DataType:    UNIT,
+		Label:       "Futures Price",
+		Description: "The current price at which a futures contract is trading on Binance.",
+		Color:       "#386061",
 	},
 	Asset.FUTURES_VOLUME: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return countDivisionsTo(priceUSDA/priceUSDB, 0.01)
 		},
-		Asset.FUTURES_VOLUME, QUANTITY, nil, nil, false,
-		"Futures Volume", "The total amount of futures contracts traded on Binance.",
-		"#0b430b",
+		ID:          Asset.FUTURES_VOLUME,
+		DataType:    QUANTITY,
+		Label:       "Futures Volume",
+		Description: "The total amount of futures contracts traded on Binance.",
+		Color:       "#0b430b",
 	},
 
 	// Technical Indicators
 	Asset.RSI: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return 2
 		},
-		Asset.RSI, POINT, []DataType{UNIT}, []reflect.Type{reflect.TypeOf(int64(0))}, false,
-		"Relative Strength Index (RSI)", "A momentum oscillator that measures the speed and change of price movements, indicating overbought or oversold conditions.",
-		"#614C97",
+		ID:                   Asset.RSI,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{{DataTypes: []DataType{UNIT}, Columns: []ColumnName{ColumnType.CLOSE}}},
+		RequiredArguments:    []ArgumentSchema{periodArgument(14, 1)},
+		Label:                "Relative Strength Index (RSI)",
+		Description:          "A momentum oscillator that measures the speed and change of price movements, indicating overbought or oversold conditions.",
+		Color:                "#614C97",
 	},
 
 	Asset.RSI2: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return 2
 		},
-		Asset.RSI2, POINT, []DataType{-1}, []reflect.Type{reflect.TypeOf(""), reflect.TypeOf(int64(0))}, false,
-		"Relative Strength Index 2 (RSI)", "A momentum oscillator that measures the speed and change of price movements, indicating overbought or oversold conditions.",
-		"#614C97",
+		ID:                   Asset.RSI2,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(14, 1)},
+		Label:                "Relative Strength Index 2 (RSI)",
+		Description:          "A momentum oscillator that measures the speed and change of price movements, indicating overbought or oversold conditions.",
+		Color:                "#614C97",
 	},
 	Asset.SMA: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return priceDecimals(priceUSDA / priceUSDB)
 		},
-		Asset.SMA, POINT, []DataType{-1}, []reflect.Type{reflect.TypeOf(""), reflect.TypeOf(int64(0))}, false,
-		"Simple Moving Average (SMA)", "A simple, arithmetic moving average that is calculated by adding the closing price of a security for a number of time periods and then dividing this total by the number of time periods.",
-		"#873e23",
+		ID:                   Asset.SMA,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(20, 1)},
+		Label:                "Simple Moving Average (SMA)",
+		Description:          "A simple, arithmetic moving average that is calculated by adding the closing price of a security for a number of time periods and then dividing this total by the number of time periods.",
+		Color:                "#873e23",
 	},
 	Asset.EMA: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return priceDecimals(priceUSDA / priceUSDB)
 		},
-		Asset.EMA, POINT, []DataType{-1}, []reflect.Type{reflect.TypeOf(""), reflect.TypeOf(int64(0))}, false,
-		"Exponential Moving Average (EMA)", "A type of moving average that is similar to a simple moving average, except that more weight is given to the latest data.",
-		"#2596be",
+		ID:                   Asset.EMA,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(20, 1)},
+		Label:                "Exponential Moving Average (EMA)",
+		Description:          "A type of moving average that is similar to a simple moving average, except that more weight is given to the latest data.",
+		Color:                "#2596be",
 	},
 	Asset.WMA: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return priceDecimals(priceUSDA / priceUSDB)
 		},
-		Asset.WMA, POINT, []DataType{-1}, []reflect.Type{reflect.TypeOf(""), reflect.TypeOf(int64(0))}, false,
-		"Weighted Moving Average (WMA)", "A moving average that gives more weight to recent prices, making it more responsive to new information.",
-		"#b5b5b5",
+		ID:                   Asset.WMA,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(20, 1)},
+		Label:                "Weighted Moving Average (WMA)",
+		Description:          "A moving average that gives more weight to recent prices, making it more responsive to new information.",
+		Color:                "#b5b5b5",
 	},
 	Asset.HMA: {
-		func(priceUSDA, priceUSDB float64) int8 {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:                   Asset.HMA,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(20, 2)},
+		Label:                "Hull Moving Average (HMA)",
+		Description:          "A moving average that is more responsive to price changes than a simple or exponential moving average.",
+		Color:                "#f1ae8a",
+	},
+	Asset.DEMA: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:                   Asset.DEMA,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(20, 1)},
+		Label:                "Double Exponential Moving Average (DEMA)",
+		Description:          "An EMA with less lag, computed as twice the EMA minus the EMA of the EMA.",
+		Color:                "#1f78b4",
+	},
+	Asset.TEMA: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:                   Asset.TEMA,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(20, 1)},
+		Label:                "Triple Exponential Moving Average (TEMA)",
+		Description:          "An EMA with even less lag than the DEMA, combining an EMA with the EMAs of its EMA.",
+		Color:                "#33a02c",
+	},
+	Asset.KAMA: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:                   Asset.KAMA,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments: []ArgumentSchema{
+			columnArgument(ColumnType.CLOSE),
+			periodArgument(10, 1),
+			intArgument("fast", 2, 1, "Period of the fastest EMA the average can follow, when the price trends."),
+			intArgument("slow", 30, 2, "Period of the slowest EMA the average can follow, when the price is noisy."),
+		},
+		Label:       "Kaufman Adaptive Moving Average (KAMA)",
+		Description: "A moving average adapting its speed to the efficiency of the price moves, fast in trends and slow in noise.",
+		Color:       "#ff7f00",
+	},
+	Asset.ZLEMA: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:                   Asset.ZLEMA,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(20, 1)},
+		Label:                "Zero Lag Exponential Moving Average (ZLEMA)",
+		Description:          "An EMA of the values corrected by their momentum over half the period, to remove most of the lag.",
+		Color:                "#6a3d9a",
+	},
+	Asset.VWMA: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:       Asset.VWMA,
+		DataType: POINT,
+		RequiredDependencies: []DependencyConstraint{
+			columnDependency("column"),
+			{DataTypes: []DataType{QUANTITY}},
+		},
+		RequiredArguments: []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(20, 1)},
+		Label:             "Volume Weighted Moving Average (VWMA)",
+		Description:       "A moving average of any column, each value being weighted by the volume traded at the same time.",
+		Color:             "#b15928",
+	},
+	Asset.MACD: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
 			return priceDecimals(priceUSDA / priceUSDB)
 		},
-		Asset.HMA, POINT, []DataType{-1}, []reflect.Type{reflect.TypeOf(""), reflect.TypeOf(int64(0))}, false,
-		"Hull Moving Average (HMA)", "A moving average that is more responsive to price changes than a simple or exponential moving average.",
-		"#f1ae8a",
-	},
-	// Asset.WA: {
-	// 	func(priceUSDA, priceUSDB float64) int8 {
-	// 		return priceDecimals(priceUSDA / priceUSDB)
-	// 	},
-	// 	Asset.WA, POINT, []DataType{UNIT, QUANTITY}, []reflect.Type{reflect.TypeOf("string"), reflect.TypeOf("string")}, true,
-	// 	"Weighted Average", "The average of a set of values, each of which is multiplied by a weight.",
-	// 	"#f1ae8a",
-	// },
+		ID:       Asset.MACD,
+		DataType: VECTOR,
+		RequiredDependencies: []DependencyConstraint{
+			{DataTypes: []DataType{UNIT, QUANTITY, POINT}, ColumnArgument: "column"},
+		},
+		RequiredArguments: []ArgumentSchema{
+			columnArgument(ColumnType.CLOSE),
+			intArgument("fast", 12, 1, "Period of the fast EMA."),
+			intArgument("slow", 26, 2, "Period of the slow EMA, greater than the fast one."),
+			intArgument("signal", 9, 1, "Period of the EMA of the MACD line."),
+		},
+		OutputColumns: macdColumns,
+		Label:         "Moving Average Convergence Divergence (MACD)",
+		Description:   "A trend-following momentum indicator showing the difference between a fast and a slow EMA, its signal line and their histogram.",
+		Color:         "#3a7bd5",
+	},
+	Asset.STDDEV: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:                   Asset.STDDEV,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(20, 2)},
+		Label:                "Standard Deviation",
+		Description:          "The population standard deviation of the values over a rolling window, a measure of volatility.",
+		Color:                "#7d5ba6",
+	},
+	Asset.BOLLINGER: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:                   Asset.BOLLINGER,
+		DataType:             VECTOR,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments: []ArgumentSchema{
+			columnArgument(ColumnType.CLOSE),
+			periodArgument(20, 2),
+			floatArgument("multiplier", 2, 0, "Number of standard deviations between the middle band and the outer bands."),
+		},
+		OutputColumns: bollingerColumns,
+		Label:         "Bollinger Bands",
+		Description:   "Volatility bands placed a number of standard deviations above and below a simple moving average, with %B and the bandwidth.",
+		Color:         "#c9a227",
+	},
+	Asset.TR: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:                   Asset.TR,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{candleDependency()},
+		Label:                "True Range",
+		Description:          "The greatest of the candle range and the gaps between the previous close and the current high and low.",
+		Color:                "#a0522d",
+	},
+	Asset.ATR: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:                   Asset.ATR,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{candleDependency()},
+		RequiredArguments:    []ArgumentSchema{periodArgument(14, 1)},
+		Label:                "Average True Range (ATR)",
+		Description:          "The true range smoothed with Wilder's moving average, a measure of volatility.",
+		Color:                "#cd853f",
+	},
+	Asset.STOCHASTIC: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 2
+		},
+		ID:                   Asset.STOCHASTIC,
+		DataType:             VECTOR,
+		RequiredDependencies: []DependencyConstraint{candleDependency()},
+		RequiredArguments: []ArgumentSchema{
+			intArgument("k_period", 14, 1, "Number of candles the highest high and the lowest low are computed on."),
+			intArgument("d_period", 3, 1, "Period of the SMA of %K."),
+		},
+		OutputColumns: stochasticColumns,
+		Label:         "Stochastic Oscillator",
+		Description:   "A momentum indicator comparing the close to the range of the last candles (%K) along with its moving average (%D).",
+		Color:         "#2e8b57",
+	},
+	Asset.WILLIAMS_R: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 2
+		},
+		ID:                   Asset.WILLIAMS_R,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{candleDependency()},
+		RequiredArguments:    []ArgumentSchema{periodArgument(14, 1)},
+		Label:                "Williams %R",
+		Description:          "A momentum indicator between -100 and 0 measuring the close relative to the highest high of the last candles.",
+		Color:                "#6b8e23",
+	},
+	Asset.CCI: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 2
+		},
+		ID:                   Asset.CCI,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{candleDependency()},
+		RequiredArguments:    []ArgumentSchema{periodArgument(20, 2)},
+		Label:                "Commodity Channel Index (CCI)",
+		Description:          "The deviation of the typical price from its moving average, relative to the mean deviation.",
+		Color:                "#b22222",
+	},
+	Asset.ZSCORE: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 4
+		},
+		ID:                   Asset.ZSCORE,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(20, 2)},
+		Label:                "Z-Score",
+		Description:          "The number of standard deviations between the value and the mean of a rolling window.",
+		Color:                "#9467bd",
+	},
+	Asset.PERCENTILE_RANK: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 2
+		},
+		ID:                   Asset.PERCENTILE_RANK,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(100, 2)},
+		Label:                "Percentile Rank",
+		Description:          "The percentage of the values of a rolling window lower than the current one, from 0 to 100.",
+		Color:                "#8c564b",
+	},
+	Asset.ROLLING_MIN: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:                   Asset.ROLLING_MIN,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.LOW), periodArgument(20, 1)},
+		Label:                "Rolling Minimum",
+		Description:          "The lowest value of a rolling window.",
+		Color:                "#d62728",
+	},
+	Asset.ROLLING_MAX: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:                   Asset.ROLLING_MAX,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.HIGH), periodArgument(20, 1)},
+		Label:                "Rolling Maximum",
+		Description:          "The highest value of a rolling window.",
+		Color:                "#2ca02c",
+	},
+	Asset.ROLLING_SUM: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return countDivisionsTo(priceUSDA/priceUSDB, 0.01)
+		},
+		ID:                   Asset.ROLLING_SUM,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{columnDependency("column")},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.PLUS), periodArgument(20, 1)},
+		Label:                "Rolling Sum",
+		Description:          "The sum of the values of a rolling window.",
+		Color:                "#7f7f7f",
+	},
+	Asset.CORRELATION: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 4
+		},
+		ID:                        Asset.CORRELATION,
+		DataType:                  POINT,
+		RequiredDependencies:      pairDependencies(),
+		RequiredArguments:         pairArguments(),
+		AllowCrossSetDependencies: true,
+		Label:                     "Correlation",
+		Description:               "The Pearson correlation between two assets over a rolling window, from -1 to 1.",
+		Color:                     "#17becf",
+	},
+	Asset.COVARIANCE: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 8
+		},
+		ID:                        Asset.COVARIANCE,
+		DataType:                  POINT,
+		RequiredDependencies:      pairDependencies(),
+		RequiredArguments:         pairArguments(),
+		AllowCrossSetDependencies: true,
+		Label:                     "Covariance",
+		Description:               "The population covariance between two assets over a rolling window.",
+		Color:                     "#bcbd22",
+	},
+	Asset.BETA: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 4
+		},
+		ID:                        Asset.BETA,
+		DataType:                  POINT,
+		RequiredDependencies:      pairDependencies(),
+		RequiredArguments:         pairArguments(),
+		AllowCrossSetDependencies: true,
+		Label:                     "Beta",
+		Description:               "The sensitivity of the first asset to the moves of the second one (ex: an altcoin to BTC) over a rolling window.",
+		Color:                     "#e377c2",
+	},
+	Asset.FORMULA: {
+		// the scale of the result depends on the expression
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 8
+		},
+		ID:                   Asset.FORMULA,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{{}},
+		RequiredArguments: []ArgumentSchema{
+			{
+				Name:        "expression",
+				Type:        ARG_STRING,
+				Default:     "$0.close",
+				Description: "Arithmetic expression (+ - * / and parentheses) over the columns of the dependencies, referenced as $<dependency index>.<column> (ex: ($0.close - $1.close) / $1.close).",
+			},
+		},
+		VariadicDependencies:      true,
+		AllowCrossSetDependencies: true,
+		Label:                     "Formula",
+		Description:               "An arithmetic expression over the columns of several assets at the same time, like a spread, a ratio or a basis.",
+		Color:                     "#8c564b",
+	},
+	Asset.BOOK_IMBALANCE: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 4
+		},
+		ID:                   Asset.BOOK_IMBALANCE,
+		DataType:             POINT,
+		RequiredDependencies: bookBandDependencies(),
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE)},
+		CheckDependencies:    checkBookBand,
+		Label:                "Book Depth Imbalance",
+		Description:          "The imbalance between the bid and ask liquidity within the same distance of the market price, from -1 (only asks) to 1 (only bids).",
+		Color:                "#2ca02c",
+	},
+	Asset.BOOK_CUMULATIVE_DEPTH: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return countDivisionsTo(priceUSDA/priceUSDB, 10)
+		},
+		ID:                   Asset.BOOK_CUMULATIVE_DEPTH,
+		DataType:             POINT,
+		RequiredDependencies: bookBandDependencies(),
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE)},
+		CheckDependencies:    checkBookBand,
+		Label:                "Cumulative Book Depth",
+		Description:          "The liquidity available on both sides of the book within a distance of the market price.",
+		Color:                "#1f77b4",
+	},
+	Asset.BOOK_SLOPE: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return countDivisionsTo(priceUSDA/priceUSDB, 10)
+		},
+		ID:                   Asset.BOOK_SLOPE,
+		DataType:             POINT,
+		RequiredDependencies: bookSideDependencies(),
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE)},
+		CheckDependencies:    checkBookSide,
+		Label:                "Book Liquidity Slope",
+		Description:          "The liquidity added by each percent away from the market price on a side of the book, from the depth of its five bands.",
+		Color:                "#ff7f0e",
+	},
+	Asset.BOOK_DEPTH_CHANGE: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 2
+		},
+		ID:                   Asset.BOOK_DEPTH_CHANGE,
+		DataType:             POINT,
+		RequiredDependencies: []DependencyConstraint{bookDepthDependency()},
+		RequiredArguments:    []ArgumentSchema{columnArgument(ColumnType.CLOSE), periodArgument(10, 1)},
+		Label:                "Book Depth Change Rate",
+		Description:          "The change of the liquidity of a book depth band in percent over a number of values.",
+		Color:                "#d62728",
+	},
+	Asset.OBV: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return countDivisionsTo(priceUSDA/priceUSDB, 0.01)
+		},
+		ID:                   Asset.OBV,
+		DataType:             POINT,
+		RequiredDependencies: priceVolumeDependencies(),
+		RequiredArguments:    []ArgumentSchema{volumeMethodArgument()},
+		Label:                "On-Balance Volume (OBV)",
+		Description:          "The cumulative volume, added when the price goes up and subtracted when it goes down (or the cumulative taker buy volume minus the taker sell volume).",
+		Color:                "#4682b4",
+	},
+	Asset.AD: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return countDivisionsTo(priceUSDA/priceUSDB, 0.01)
+		},
+		ID:                   Asset.AD,
+		DataType:             POINT,
+		RequiredDependencies: priceVolumeDependencies(),
+		Label:                "Accumulation/Distribution Line (A/D)",
+		Description:          "The cumulative volume weighted by the position of the close in the range of each candle.",
+		Color:                "#5f9ea0",
+	},
+	Asset.MFI: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 2
+		},
+		ID:                   Asset.MFI,
+		DataType:             POINT,
+		RequiredDependencies: priceVolumeDependencies(),
+		RequiredArguments:    []ArgumentSchema{periodArgument(14, 1), volumeMethodArgument()},
+		Label:                "Money Flow Index (MFI)",
+		Description:          "A volume-weighted RSI between 0 and 100, comparing the money flowing in and out of the asset.",
+		Color:                "#8a2be2",
+	},
+	Asset.CMF: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return 4
+		},
+		ID:                   Asset.CMF,
+		DataType:             POINT,
+		RequiredDependencies: priceVolumeDependencies(),
+		RequiredArguments:    []ArgumentSchema{periodArgument(20, 1)},
+		Label:                "Chaikin Money Flow (CMF)",
+		Description:          "The volume weighted by the position of the close in each candle, relative to the total volume of the window.",
+		Color:                "#20b2aa",
+	},
+	Asset.WA: {
+		SetUpDecimals: func(priceUSDA, priceUSDB float64) int8 {
+			return priceDecimals(priceUSDA / priceUSDB)
+		},
+		ID:       Asset.WA,
+		DataType: POINT,
+		RequiredDependencies: []DependencyConstraint{
+			{DataTypes: []DataType{UNIT}, ColumnArgument: "column"},
+			{DataTypes: []DataType{QUANTITY}},
+		},
+		RequiredArguments:     []ArgumentSchema{columnArgument(ColumnType.CLOSE)},
+		IndexedOnMinTimeframe: true,
+		Label:                 "Volume Weighted Average (VWAP)",
+		Description:           "The average price of each candle, each price of the minimum timeframe being weighted by the volume traded at the same time.",
+		Color:                 "#f1ae8a",
+	},
 }
 
 type AvailableAssetJSON struct {
@@ -382,11 +920,16 @@ type AvailableAssetJSON struct {
 	DataType        DataType  `json:"data_type"`
 	DefaultDecimals int8      `json:"default_decimals"`
 
-	Dependencies  []DataType `json:"dependencies"`
-	ArgumentTypes []string   `json:"argument_types"`
-	Label         string     `json:"label"`
-	Description   string     `json:"description"`
-	Color         string     `json:"color"`
+	Dependencies          []DataType             `json:"dependencies"`
+	DependencyConstraints []DependencyConstraint `json:"dependency_constraints"`
+	ArgumentTypes         []string               `json:"argument_types"`
+	Arguments             []ArgumentSchema       `json:"arguments"`
+	OutputColumns         []ColumnName           `json:"output_columns"`
+	CrossSetDependencies  bool                   `json:"cross_set_dependencies"`
+	VariadicDependencies  bool                   `json:"variadic_dependencies"`
+	Label                 string                 `json:"label"`
+	Description           string                 `json:"description"`
+	Color                 string                 `json:"color"`
 
 	DataTypeName        string       `json:"data_type_name"`
 	DataTypeColor       string       `json:"data_type_color"`
@@ -398,24 +941,34 @@ func (aa AvailableAssets) JSON() []AvailableAssetJSON {
 	ret := []AvailableAssetJSON{}
 	for _, v := range aa {
 		argumentTypes := []string{}
-		for _, arg := range v.RequiredArgumentTypes {
-			argumentTypes = append(argumentTypes, arg.String())
+		for _, arg := range v.RequiredArguments {
+			argumentTypes = append(argumentTypes, arg.Type.legacyTypeName())
+		}
+
+		dependencies := []DataType{}
+		for _, dep := range v.RequiredDependencies {
+			dependencies = append(dependencies, dep.DataType())
 		}
 
 		dataType := v.DataType
 
 		ret = append(ret, AvailableAssetJSON{
-			AssetType:           v.ID,
-			DataType:            v.DataType,
-			DataTypeName:        When[string](dataType == -1).Then("").Else(dataType.String()),
-			DataTypeColor:       When[string](dataType == -1).Then("").Else(dataType.Color()),
-			DataTypeColumns:     When[[]ColumnName](dataType == -1).Then([]ColumnName{}).Else(dataType.Columns()),
-			DataTypeDescription: When[string](dataType == -1).Then("").Else(dataType.Description()),
-			Dependencies:        v.RequiredDependencyDataTypes,
-			ArgumentTypes:       argumentTypes,
-			Label:               v.Label,
-			Description:         v.Description,
-			Color:               v.Color,
+			AssetType:             v.ID,
+			DataType:              v.DataType,
+			DataTypeName:          When[string](dataType == -1).Then("").Else(dataType.String()),
+			DataTypeColor:         When[string](dataType == -1).Then("").Else(dataType.Color()),
+			DataTypeColumns:       When[[]ColumnName](dataType == -1).Then([]ColumnName{}).Else(v.Columns()),
+			DataTypeDescription:   When[string](dataType == -1).Then("").Else(dataType.Description()),
+			Dependencies:          dependencies,
+			DependencyConstraints: When[[]DependencyConstraint](v.RequiredDependencies == nil).Then([]DependencyConstraint{}).Else(v.RequiredDependencies),
+			ArgumentTypes:         argumentTypes,
+			Arguments:             When[[]ArgumentSchema](v.RequiredArguments == nil).Then([]ArgumentSchema{}).Else(v.RequiredArguments),
+			OutputColumns:         When[[]ColumnName](v.OutputColumns == nil).Then([]ColumnName{}).Else(v.OutputColumns),
+			CrossSetDependencies:  v.AllowCrossSetDependencies,
+			VariadicDependencies:  v.VariadicDependencies,
+			Label:                 v.Label,
+			Description:           v.Description,
+			Color:                 v.Color,
 		})
 	}
 	return ret
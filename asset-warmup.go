@@ -0,0 +1,67 @@
+package pcommon
+
+import (
+	"fmt"
+	"time"
+)
+
+/*
+	Warm-up of an asset: the number of consecutive values its dependencies must provide, on the timeframe it is computed on,
+	before its first valid value. Dependencies warm up first, so the warm-up of an indicator adds up with the longest one
+	of its dependencies.
+
+	To produce valid values from MinDataDate, a backfill starts from WarmupStart instead.
+*/
+
+// WarmupPeriod returns the number of values needed for the first value of the asset (1 for assets without indicator).
+// An indicator needing n values of a dependency which needs m values itself needs n + m - 1 values.
+func (adp AssetAddressParsed) WarmupPeriod() (int, error) {
+	factory := getIndicatorFactory(adp.AssetType)
+	if factory == nil {
+		return 1, nil
+	}
+
+	config, _ := GetAssetConfig(adp.AssetType)
+	args, err := parseIndicatorArguments(config.RequiredArguments, adp.Arguments)
+	if err != nil {
+		return 0, fmt.Errorf("invalid argument for %s: %s", adp.AssetType, err)
+	}
+	indicator := factory()
+	if err := indicator.Init(args); err != nil {
+		return 0, fmt.Errorf("invalid argument for %s: %s", adp.AssetType, err)
+	}
+
+	dependenciesWarmup := 1
+	for _, depAddr := range adp.Dependencies {
+		dep, err := depAddr.Parse()
+		if err != nil {
+			return 0, err
+		}
+		warmup, err := dep.WarmupPeriod()
+		if err != nil {
+			return 0, err
+		}
+		if warmup > dependenciesWarmup {
+			dependenciesWarmup = warmup
+		}
+	}
+	return indicator.WarmupPeriod() + dependenciesWarmup - 1, nil
+}
+
+// WarmupStart returns the time from which the asset must be computed on the timeframe for its first valid value to be at from.
+func (adp AssetAddressParsed) WarmupStart(from TimeUnit, timeframe time.Duration) (TimeUnit, error) {
+	warmup, err := adp.WarmupPeriod()
+	if err != nil {
+		return 0, err
+	}
+	return from.Add(-time.Duration(warmup-1) * timeframe), nil
+}
+
+// BackfillStart returns the time from which the asset of the set must be computed on the timeframe to be valid from MinDataDate.
+func (as AssetSettings) BackfillStart(setID []string, timeframe time.Duration) (TimeUnit, error) {
+	minDataDate, err := Format.StrDateToDate(as.MinDataDate)
+	if err != nil {
+		return 0, err
+	}
+	return as.Address.AddSetID(setID).WarmupStart(NewTimeUnitFromTime(minDataDate), timeframe)
+}
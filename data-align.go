@@ -0,0 +1,64 @@
+package pcommon
+
+// AlignDataLists joins lists sorted by ascending time on the time of their values:
+// each row holds one value per list, all at the same time, and times missing from a list are dropped.
+func AlignDataLists(lists ...DataList) [][]Data {
+	values := make([][]Data, len(lists))
+	for i, list := range lists {
+		if list == nil || list.Len() == 0 {
+			return [][]Data{}
+		}
+		values[i] = list.Map()
+	}
+	return alignData(values)
+}
+
+// alignData is AlignDataLists on the values of the lists.
+func alignData(values [][]Data) [][]Data {
+	if len(values) == 0 {
+		return nil
+	}
+	for _, v := range values {
+		if len(v) == 0 {
+			return [][]Data{}
+		}
+	}
+
+	ret := make([][]Data, 0, len(values[0]))
+	positions := make([]int, len(values))
+	for {
+		// the latest time among the current values of every list
+		var latest TimeUnit
+		for i, pos := range positions {
+			if pos >= len(values[i]) {
+				return ret
+			}
+			if t := values[i][pos].GetTime(); t > latest {
+				latest = t
+			}
+		}
+
+		aligned := true
+		for i := range positions {
+			for positions[i] < len(values[i]) && values[i][positions[i]].GetTime() < latest {
+				positions[i]++
+			}
+			if positions[i] >= len(values[i]) {
+				return ret
+			}
+			if values[i][positions[i]].GetTime() != latest {
+				aligned = false
+			}
+		}
+		if !aligned {
+			continue
+		}
+
+		row := make([]Data, len(values))
+		for i := range positions {
+			row[i] = values[i][positions[i]]
+			positions[i]++
+		}
+		ret = append(ret, row)
+	}
+}
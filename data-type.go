@@ -2,6 +2,7 @@ package pcommon
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"time"
 )
@@ -17,6 +18,9 @@ const QUANTITY DataType = 2
 // points are simple data (a float64) that cannot be aggregated or summed
 const POINT DataType = 3
 
+// vectors are a set of named values (the output columns of their asset) that cannot be aggregated or summed
+const VECTOR DataType = 4
+
 type Data interface {
 	CSVLine(volumeDecimals int8, requirement CSVCheckListRequirement) []string
 	ToRaw(decimals int8) []byte
@@ -65,6 +69,9 @@ func NewTypeTimeArray(t DataType) DataList {
 	if t == POINT {
 		return PointTimeArray{}
 	}
+	if t == VECTOR {
+		return VectorTimeArray{}
+	}
 	return nil
 }
 
@@ -78,6 +85,9 @@ func (d DataType) Description() string {
 	if d == POINT {
 		return "Point: simple data (a float64) that cannot be aggregated or summed, it's in general the derivation of a unit's or quantity's column"
 	}
+	if d == VECTOR {
+		return "Vector: a set of named values computed together (ex: the lines of an indicator) that cannot be aggregated or summed"
+	}
 	log.Fatal("unknown data type")
 	return ""
 }
@@ -92,6 +102,9 @@ func (d DataType) String() string {
 	if d == POINT {
 		return "point"
 	}
+	if d == VECTOR {
+		return "vector"
+	}
 	log.Fatal("unknown data type")
 	return ""
 }
@@ -106,6 +119,9 @@ func (d DataType) Color() string {
 	if d == POINT {
 		return "#8000ff"
 	}
+	if d == VECTOR {
+		return "#cc0066"
+	}
 	log.Fatal("unknown data type")
 	return ""
 }
@@ -124,9 +140,28 @@ func ParseTypeData(t DataType, d []byte, dataTime TimeUnit) (Data, error) {
 		}
 		return p.ToTime(dataTime), nil
 	}
+	if t == VECTOR {
+		return nil, errors.New("vector data needs the output columns of its asset: use ParseAssetData")
+	}
 	return nil, errors.New("unknown data type")
 }
 
+// ParseAssetData parses the data of an asset, the output columns of vector assets are read from its config.
+func ParseAssetData(assetType AssetType, d []byte, dataTime TimeUnit) (Data, error) {
+	config, ok := GetAssetConfig(assetType)
+	if !ok {
+		return nil, fmt.Errorf("asset type %s is not registered", assetType)
+	}
+	if config.DataType == VECTOR {
+		v, err := ParseRawVector(d, config.OutputColumns)
+		if err != nil {
+			return nil, err
+		}
+		return v.ToTime(dataTime), nil
+	}
+	return ParseTypeData(config.DataType, d, dataTime)
+}
+
 func (d DataType) Columns() []ColumnName {
 	if d == UNIT {
 		return []ColumnName{ColumnType.TIME, ColumnType.OPEN, ColumnType.HIGH, ColumnType.LOW, ColumnType.CLOSE, ColumnType.AVERAGE, ColumnType.MEDIAN, ColumnType.ABSOLUTE_SUM, ColumnType.COUNT}
@@ -137,12 +172,20 @@ func (d DataType) Columns() []ColumnName {
 	if d == POINT {
 		return []ColumnName{ColumnType.TIME, ColumnType.VALUE}
 	}
+	if d == VECTOR {
+		// the other columns are the output columns of the asset (see AssetStateConfig.Columns)
+		return []ColumnName{ColumnType.TIME}
+	}
 	return []ColumnName{}
 }
 
 func (q DataType) Header(prefix string, requirement CSVCheckListRequirement) []string {
+	return columnsHeader(q.Columns(), prefix, requirement)
+}
+
+func columnsHeader(columns []ColumnName, prefix string, requirement CSVCheckListRequirement) []string {
 	list := []string{}
-	for _, column := range q.Columns() {
+	for _, column := range columns {
 		if requirement[column] {
 			if column == ColumnType.VALUE {
 				list = append(list, prefix)
@@ -0,0 +1,228 @@
+package pcommon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+// Vector is a set of named values computed at the same time (ex: the lines of an indicator).
+// Columns are the output columns of the asset and are shared by every vector of the asset.
+type Vector struct {
+	Columns []ColumnName
+	Values  []float64
+}
+
+type VectorTime struct {
+	Vector
+	Time TimeUnit
+}
+
+type VectorTimeArray []VectorTime
+
+func NewVector(columns []ColumnName, values []float64) Vector {
+	return Vector{Columns: columns, Values: values}
+}
+
+func (lst VectorTimeArray) Reverse() DataList {
+	ret := make(VectorTimeArray, len(lst))
+	for i, v := range lst {
+		ret[len(lst)-1-i] = v
+	}
+	return ret
+}
+
+func (lst VectorTimeArray) Aggregate(timeframe time.Duration, newTime TimeUnit) Data {
+	log.Fatal("no aggregation for vectors data")
+	return VectorTime{}
+}
+
+func (lst VectorTimeArray) Map() []Data {
+	ret := make([]Data, len(lst))
+	for i, v := range lst {
+		ret[i] = v
+	}
+	return ret
+}
+
+func (list VectorTimeArray) ToJSON(columns []ColumnName) ([]map[ColumnName]interface{}, error) {
+	ret := make([]map[ColumnName]interface{}, 0, len(list))
+	for _, v := range list {
+		item := make(map[ColumnName]interface{}, len(columns))
+		for _, col := range columns {
+			if col == ColumnType.TIME {
+				item[col] = v.Time
+				continue
+			}
+			value, err := v.ValueAt(col)
+			if err != nil {
+				return nil, err
+			}
+			item[col] = value
+		}
+		ret = append(ret, item)
+	}
+	return ret, nil
+}
+
+func (lst VectorTimeArray) Append(pt Data) DataList {
+	return append(lst, pt.(VectorTime))
+}
+
+func (lst VectorTimeArray) Prepend(pt Data) DataList {
+	return append(VectorTimeArray{pt.(VectorTime)}, lst...)
+}
+
+func (lst VectorTimeArray) RemoveFirstN(n int) DataList {
+	if n >= len(lst) {
+		return VectorTimeArray{}
+	}
+	return lst[n:]
+}
+
+func (lst VectorTimeArray) First() Data {
+	if len(lst) == 0 {
+		return nil
+	}
+	return &lst[0]
+}
+
+func (lst VectorTimeArray) Last() Data {
+	if len(lst) == 0 {
+		return nil
+	}
+	return &lst[len(lst)-1]
+}
+
+func (lst VectorTimeArray) Len() int {
+	if lst == nil {
+		return 0
+	}
+	return len(lst)
+}
+
+func (lst VectorTimeArray) ToRaw(decimal int8) map[TimeUnit][]byte {
+	ret := make(map[TimeUnit][]byte)
+	for _, v := range lst {
+		ret[v.Time] = v.ToRaw(decimal)
+	}
+	return ret
+}
+
+func (v Vector) Type() DataType {
+	return VECTOR
+}
+
+func (v Vector) IsEmpty() bool {
+	return len(v.Values) == 0
+}
+
+// ParseRawVector parses the values of a vector, columns are the output columns of its asset.
+func ParseRawVector(raw []byte, columns []ColumnName) (Vector, error) {
+	if len(raw) == 0 {
+		return Vector{Columns: columns}, nil
+	}
+	splited := strings.Split(string(raw), "@")
+	if len(splited) != len(columns) {
+		return Vector{}, fmt.Errorf("expected %d vector values, got %d", len(columns), len(splited))
+	}
+	values := make([]float64, len(splited))
+	for i, s := range splited {
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return Vector{}, err
+		}
+		values[i] = v
+	}
+	return NewVector(columns, values), nil
+}
+
+func (v Vector) ToTime(time TimeUnit) VectorTime {
+	return VectorTime{Vector: v, Time: time}
+}
+
+// ToRaw encodes the values in the order of the columns, separated by '@' like units.
+func (v Vector) ToRaw(decimals int8) []byte {
+	values := lo.Map(v.Values, func(value float64, _ int) string {
+		return Format.Float(value, decimals)
+	})
+	return []byte(strings.Join(values, "@"))
+}
+
+func (v Vector) MarshalJSON() ([]byte, error) {
+	if len(v.Columns) != len(v.Values) {
+		return nil, errors.New("vector columns and values do not match")
+	}
+	ret := make(map[ColumnName]float64, len(v.Values))
+	for i, column := range v.Columns {
+		ret[column] = v.Values[i]
+	}
+	return json.Marshal(ret)
+}
+
+func (v VectorTime) MarshalJSON() ([]byte, error) {
+	if len(v.Columns) != len(v.Values) {
+		return nil, errors.New("vector columns and values do not match")
+	}
+	ret := make(map[ColumnName]interface{}, len(v.Values)+1)
+	ret[ColumnType.TIME] = v.Time
+	for i, column := range v.Columns {
+		ret[column] = v.Values[i]
+	}
+	return json.Marshal(ret)
+}
+
+func (v VectorTime) GetTime() TimeUnit {
+	return v.Time
+}
+
+func (v VectorTime) Min() float64 {
+	return lo.Min(v.Values)
+}
+
+func (v VectorTime) Max() float64 {
+	return lo.Max(v.Values)
+}
+
+func (v VectorTime) ValueAt(column ColumnName) (float64, error) {
+	idx := lo.IndexOf(v.Columns, column)
+	if idx == -1 || idx >= len(v.Values) {
+		return 0.00, fmt.Errorf("column %s not found", column)
+	}
+	return v.Values[idx], nil
+}
+
+func (v VectorTime) CSVLine(volumeDecimals int8, requirement CSVCheckListRequirement) []string {
+	ret := []string{}
+
+	if requirement[ColumnType.TIME] {
+		if v.Time > 0 {
+			if Env.MIN_TIME_FRAME >= time.Second {
+				ret = append(ret, strconv.FormatInt(v.Time.ToTime().Unix(), 10))
+			} else {
+				ret = append(ret, v.Time.String())
+			}
+		} else {
+			ret = append(ret, "")
+		}
+	}
+
+	// unlike points, 0 is a valid value of a vector column (ex: a MACD histogram)
+	for i, column := range v.Columns {
+		if requirement[column] {
+			if i < len(v.Values) {
+				ret = append(ret, Format.Float(v.Values[i], volumeDecimals))
+			} else {
+				ret = append(ret, "")
+			}
+		}
+	}
+
+	return ret
+}
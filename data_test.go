@@ -164,3 +164,38 @@ func TestUnitQuantity(t *testing.T) {
 	assert.Equal(t, newQty2.MinusCount, int64(1), "MinusCount should be 1")
 
 }
+
+func TestVector(t *testing.T) {
+	Env.MIN_TIME_FRAME = time.Second
+	columns := []ColumnName{"upper", "middle", "lower"}
+	vt := NewTimeUnit(1587607201)
+	v := NewVector(columns, []float64{105.25, 100, 0}).ToTime(vt)
+
+	parsed, err := ParseRawVector(v.ToRaw(2), columns)
+	assert.Nil(t, err)
+	assert.Equal(t, v.Vector, parsed)
+
+	_, err = ParseRawVector([]byte("1@2"), columns)
+	assert.NotNil(t, err, "the number of values must match the columns")
+
+	middle, err := v.ValueAt("middle")
+	assert.Nil(t, err)
+	assert.Equal(t, 100.0, middle)
+	_, err = v.ValueAt(ColumnType.CLOSE)
+	assert.NotNil(t, err)
+	assert.Equal(t, 0.0, v.Min())
+	assert.Equal(t, 105.25, v.Max())
+
+	requirement := CSVCheckListRequirement{ColumnType.TIME: true, "upper": true, "lower": true}
+	assert.Equal(t, []string{"1587607201", "105.25", "0"}, v.CSVLine(2, requirement))
+
+	JSON, err := json.Marshal(v)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"time":1587607201000,"upper":105.25,"middle":100,"lower":0}`, string(JSON))
+
+	list, err := VectorTimeArray{v}.ToJSON([]ColumnName{ColumnType.TIME, "lower"})
+	assert.Nil(t, err)
+	assert.Equal(t, []map[ColumnName]interface{}{{ColumnType.TIME: vt, "lower": 0.0}}, list)
+	_, err = VectorTimeArray{v}.ToJSON([]ColumnName{ColumnType.VALUE})
+	assert.NotNil(t, err)
+}
@@ -1,22 +1,141 @@
 package pcommon
 
 import (
-	"encoding/json"
 	"errors"
-	"log"
-	"reflect"
-	"strconv"
-	"strings"
+	"fmt"
+
+	"github.com/samber/lo"
 )
 
+// Indicator computes the values of an asset from the values of its dependency, one value at a time.
+// It must also be a PointIndicator or a VectorIndicator, depending on the data type of the asset.
+type Indicator interface {
+	// Init sets the indicator up with the arguments of the asset, before any state is loaded.
+	Init(args IndicatorArguments) error
+	MarshalState() ([]byte, error)
+	UnmarshalState(state []byte) error
+	// WarmupPeriod returns the number of values the indicator needs before being ready.
+	WarmupPeriod() int
+}
+
+// PointIndicator is the indicator of a POINT asset.
+type PointIndicator interface {
+	Indicator
+	// Update computes the value of the indicator at the time of data, ready is false while the indicator is warming up.
+	Update(data Data) (p Point, ready bool)
+}
+
+// MultiPointIndicator is the indicator of a POINT asset with several dependencies.
+type MultiPointIndicator interface {
+	Indicator
+	// UpdateAll computes the value of the indicator from one value per dependency, all at the same time.
+	UpdateAll(dataList []Data) (p Point, ready bool)
+}
+
+// MinTimeframeIndicator is the indicator of a POINT asset indexed on the minimum timeframe (see AssetStateConfig.IndexedOnMinTimeframe).
+// It computes one value per candle of a timeframe from the values of the minimum timeframe the candle contains.
+type MinTimeframeIndicator interface {
+	MultiPointIndicator
+	// UpdateCandle computes the value of a candle from its values of the minimum timeframe, sorted by time:
+	// each row holds one value per dependency, all at the same time.
+	UpdateCandle(rows [][]Data) (p Point, ready bool)
+}
+
+// VectorIndicator is the indicator of a VECTOR asset.
+type VectorIndicator interface {
+	Indicator
+	// UpdateVector computes one value per output column of the asset, in the same order.
+	// The returned slice belongs to the caller, it must not be kept by the indicator.
+	UpdateVector(data Data) (values []float64, ready bool)
+}
+
+// ArgumentDependenciesIndicator is implemented by indicators whose dependencies are set by their arguments
+// (ex: the references of a formula), it is required by assets with variadic dependencies.
+type ArgumentDependenciesIndicator interface {
+	Indicator
+	// DependencyColumns returns, once initialized, the columns read from each dependency.
+	// Its length is the number of dependencies the asset must have.
+	DependencyColumns() [][]ColumnName
+}
+
+// IndicatorFactory returns a new indicator, not initialized.
+type IndicatorFactory func() Indicator
+
+// IndicatorArguments are the arguments of an asset parsed with the argument schemas of its config.
+type IndicatorArguments struct {
+	schemas []ArgumentSchema
+	values  []interface{}
+}
+
+func parseIndicatorArguments(schemas []ArgumentSchema, arguments []string) (IndicatorArguments, error) {
+	if len(schemas) != len(arguments) {
+		return IndicatorArguments{}, errors.New("invalid number of arguments")
+	}
+	values := make([]interface{}, len(arguments))
+	for i, arg := range arguments {
+		v, err := schemas[i].Parse(arg)
+		if err != nil {
+			return IndicatorArguments{}, err
+		}
+		values[i] = v
+	}
+	return IndicatorArguments{schemas: schemas, values: values}, nil
+}
+
+func (a IndicatorArguments) get(name string) interface{} {
+	for i, schema := range a.schemas {
+		if schema.Name == name {
+			return a.values[i]
+		}
+	}
+	return nil
+}
+
+func (a IndicatorArguments) Has(name string) bool {
+	return a.get(name) != nil
+}
+
+func (a IndicatorArguments) Int(name string) int64 {
+	v, _ := a.get(name).(int64)
+	return v
+}
+
+func (a IndicatorArguments) Float(name string) float64 {
+	v, _ := a.get(name).(float64)
+	return v
+}
+
+func (a IndicatorArguments) Bool(name string) bool {
+	v, _ := a.get(name).(bool)
+	return v
+}
+
+func (a IndicatorArguments) String(name string) string {
+	v, _ := a.get(name).(string)
+	return v
+}
+
+func (a IndicatorArguments) Column(name string) ColumnName {
+	return ColumnName(a.String(name))
+}
+
 type IndicatorDataBuilder struct {
 	assetType AssetType
 	prevState []byte
 	arguments []string
 	Precision int8
 
-	cachedParsedState interface{}
-	cachedArguments   []interface{}
+	indicator Indicator
+	// output columns of vector assets
+	outputColumns []ColumnName
+	// columns each dependency must expose
+	columns [][]ColumnName
+	// error that occurred while setting the indicator up
+	initErr error
+
+	// nil if no checkpoint is recorded (see SetCheckpointRetention)
+	retention   *CheckpointRetention
+	checkpoints []IndicatorCheckpoint
 }
 
 func NewIndicatorDataBuilder(assetType AssetType, prevState []byte, arguments []string, precision int8) *IndicatorDataBuilder {
@@ -25,64 +144,64 @@ func NewIndicatorDataBuilder(assetType AssetType, prevState []byte, arguments []
 		prevState: prevState,
 		arguments: arguments,
 		Precision: precision,
-
-		cachedParsedState: nil,
-		cachedArguments:   nil,
-	}
-
-	indicatorConfig := DEFAULT_ASSETS[assetType]
-	ret := make([]interface{}, len(arguments))
-	for i, arg := range arguments {
-		requiredArgType := indicatorConfig.RequiredArgumentTypes[i].String()
-		if strings.HasPrefix(requiredArgType, "int") {
-			ret[i], _ = strconv.ParseInt(arg, 10, 64)
-		} else if strings.HasPrefix(requiredArgType, "bool") {
-			ret[i], _ = strconv.ParseBool(arg)
-		} else if strings.HasPrefix(requiredArgType, "float") {
-			ret[i], _ = strconv.ParseFloat(arg, 64)
-		} else {
-			ret[i] = arg
-		}
 	}
-	b.cachedArguments = ret
+	b.initErr = b.init()
 	return b
 }
 
-func parseIndicatorState[T any](b *IndicatorDataBuilder) (*T, error) {
-	if b.cachedParsedState != nil {
-		return b.cachedParsedState.(*T), nil
+func (b *IndicatorDataBuilder) init() error {
+	config, ok := GetAssetConfig(b.assetType)
+	if !ok {
+		return fmt.Errorf("asset type %s is not registered", b.assetType)
+	}
+	factory := getIndicatorFactory(b.assetType)
+	if factory == nil {
+		return errors.New("not implemented")
 	}
 
-	data := b.prevState
+	args, err := parseIndicatorArguments(config.RequiredArguments, b.arguments)
+	if err != nil {
+		return err
+	}
 
-	var zeroValue T
+	b.columns = nil
 
-	if len(data) == 0 {
-		// Check if T is a struct type with reflect, then instantiate it and return
-		t := reflect.TypeOf(zeroValue)
-		if t.Kind() == reflect.Struct {
-			b.cachedParsedState = zeroValue
-			return &zeroValue, nil
-		}
-		return nil, errors.New("provided type is not a struct")
+	b.outputColumns = config.OutputColumns
+	b.indicator = factory()
+	if err := b.indicator.Init(args); err != nil {
+		return err
 	}
 
-	var result T
-	err := json.Unmarshal(data, &result)
-	if err != nil {
-		return nil, err
+	var argumentColumns [][]ColumnName
+	count := len(config.RequiredDependencies)
+	if indicator, ok := b.indicator.(ArgumentDependenciesIndicator); ok {
+		argumentColumns = indicator.DependencyColumns()
+		count = len(argumentColumns)
 	}
-	b.cachedParsedState = &result
-	return b.cachedParsedState.(*T), nil
+	for i := 0; i < count; i++ {
+		dep := config.dependencyConstraint(i)
+		columns := append([]ColumnName{}, dep.Columns...)
+		if dep.ColumnArgument != "" {
+			columns = append(columns, args.Column(dep.ColumnArgument))
+		}
+		if i < len(argumentColumns) {
+			columns = append(columns, argumentColumns[i]...)
+		}
+		b.columns = append(b.columns, columns)
+	}
+	if len(b.prevState) > 0 {
+		return b.indicator.UnmarshalState(b.prevState)
+	}
+	return nil
 }
 
-func (b *IndicatorDataBuilder) saveState(state interface{}) {
-	data, err := json.Marshal(state)
+func (b *IndicatorDataBuilder) saveState() error {
+	data, err := b.indicator.MarshalState()
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	b.prevState = data
-	b.cachedParsedState = state
+	return nil
 }
 
 func (b *IndicatorDataBuilder) PrevState() []byte {
@@ -94,71 +213,258 @@ func (b *IndicatorDataBuilder) PrevState() []byte {
 	return stateCopy
 }
 
-func (b *IndicatorDataBuilder) ComputeUnsafe(dataList ...Data) (*Point, error) {
+func (b *IndicatorDataBuilder) WarmupPeriod() int {
+	if b.initErr != nil {
+		return 0
+	}
+	return b.indicator.WarmupPeriod()
+}
 
-	var p *Point = nil
+// checkColumns returns an error if a value of dataList misses a column required by the indicator.
+func (b *IndicatorDataBuilder) checkColumns(dataList []Data) error {
+	if len(dataList) != len(b.columns) {
+		return fmt.Errorf("expected %d dependency values, got %d", len(b.columns), len(dataList))
+	}
+	for i, data := range dataList {
+		if data == nil {
+			return fmt.Errorf("dependency value %d is missing", i)
+		}
+		for _, column := range b.columns[i] {
+			if _, err := data.ValueAt(column); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-	if b.assetType == Asset.RSI {
-		state, err := parseIndicatorState[rsiState](b)
-		if err != nil {
-			return nil, err
+// checkSameTime returns an error if the dependency values are not at the same time.
+func checkSameTime(dataList []Data) error {
+	for _, data := range dataList[1:] {
+		if data.GetTime() != dataList[0].GetTime() {
+			return errors.New("dependency values must have the same time")
 		}
-		defer b.saveState(state)
-		p = state.buildRSI(dataList[0].(UnitTime).Close, b.cachedArguments[0].(int64))
 	}
+	return nil
+}
 
-	if b.assetType == Asset.RSI2 {
-		state, err := parseIndicatorState[rsiState](b)
-		if err != nil {
+func (b *IndicatorDataBuilder) round(p Point) Point {
+	if b.Precision >= 0 {
+		p.Value = Math.RoundFloat(p.Value, uint(b.Precision))
+	}
+	return p
+}
+
+// ComputeUnsafe computes the next value of the indicator of a POINT asset, from one value per dependency.
+// A nil point is returned while the indicator is warming up: nil points must not be stored.
+// Empty values (ex: a candle without trade) are passed to the indicator like any other value.
+func (b *IndicatorDataBuilder) ComputeUnsafe(dataList ...Data) (*Point, error) {
+	update, err := b.pointUpdate()
+	if err != nil {
+		return nil, err
+	}
+	if err := b.checkColumns(dataList); err != nil {
+		return nil, err
+	}
+	if err := checkSameTime(dataList); err != nil {
+		return nil, err
+	}
+
+	p, ready := update(dataList)
+	if err := b.saveState(); err != nil {
+		return nil, err
+	}
+	if b.isCheckpointDue(dataList[0].GetTime()) {
+		b.addCheckpoint(dataList[0].GetTime(), b.prevState)
+	}
+	if !ready {
+		return nil, nil
+	}
+
+	p = b.round(p)
+	return &p, nil
+}
+
+// ComputeCandleUnsafe computes the value of the indicator of an asset indexed on the minimum timeframe on the candle starting at start,
+// from the values of the minimum timeframe it contains (see MinTimeframeIndicator).
+// A nil point is returned if the indicator is not ready on the candle.
+func (b *IndicatorDataBuilder) ComputeCandleUnsafe(start TimeUnit, rows [][]Data) (*Point, error) {
+	if b.initErr != nil {
+		return nil, b.initErr
+	}
+	indicator, ok := b.indicator.(MinTimeframeIndicator)
+	if !ok {
+		return nil, fmt.Errorf("asset type %s is not indexed on the minimum timeframe", b.assetType)
+	}
+	for _, dataList := range rows {
+		if err := b.checkColumns(dataList); err != nil {
 			return nil, err
 		}
-		defer b.saveState(state)
-		column := b.cachedArguments[0].(string)
-		v, err := dataList[0].ValueAt(ColumnName(column))
-		if err != nil {
+		if err := checkSameTime(dataList); err != nil {
 			return nil, err
 		}
-		p = state.buildRSI(v, b.cachedArguments[1].(int64))
 	}
 
-	if b.assetType == Asset.SMA || b.assetType == Asset.EMA || b.assetType == Asset.WMA || b.assetType == Asset.HMA {
-		var state *maState = nil
-		if b.prevState == nil || len(b.prevState) == 0 {
-			s := newEmptyMAState(int(b.cachedArguments[1].(int64)))
-			state = &s
-		} else {
-			var err error
-			state, err = parseIndicatorState[maState](b)
-			if err != nil {
-				return nil, err
-			}
+	p, ready := indicator.UpdateCandle(rows)
+	if err := b.saveState(); err != nil {
+		return nil, err
+	}
+	if b.isCheckpointDue(start) {
+		b.addCheckpoint(start, b.prevState)
+	}
+	if !ready {
+		return nil, nil
+	}
+
+	p = b.round(p)
+	return &p, nil
+}
+
+// ComputeVectorUnsafe computes the next value of the indicator of a VECTOR asset.
+// A nil vector is returned while the indicator is warming up.
+func (b *IndicatorDataBuilder) ComputeVectorUnsafe(dataList ...Data) (*Vector, error) {
+	indicator, err := b.vectorIndicator()
+	if err != nil {
+		return nil, err
+	}
+	if err := b.checkColumns(dataList); err != nil {
+		return nil, err
+	}
+
+	values, ready := indicator.UpdateVector(dataList[0])
+	if err := b.saveState(); err != nil {
+		return nil, err
+	}
+	if b.isCheckpointDue(dataList[0].GetTime()) {
+		b.addCheckpoint(dataList[0].GetTime(), b.prevState)
+	}
+	if !ready {
+		return nil, nil
+	}
+
+	v := b.roundVector(values)
+	return &v, nil
+}
+
+// ComputeBatch runs the indicator of a POINT asset with a single dependency over a whole series in memory
+// and saves the state once, at the end.
+// Every value is computed like in ComputeUnsafe, and no point is returned while the indicator is warming up.
+func (b *IndicatorDataBuilder) ComputeBatch(list DataList) (PointTimeArray, []byte, error) {
+	return b.ComputeBatchAll(list)
+}
+
+// ComputeBatchAll is ComputeBatch for the indicators of several dependencies.
+// lists holds one series per dependency, sorted by time: only the times present in every series are computed (see AlignDataLists).
+func (b *IndicatorDataBuilder) ComputeBatchAll(lists ...DataList) (PointTimeArray, []byte, error) {
+	update, err := b.pointUpdate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ret := PointTimeArray{}
+	err = b.batch(lists, func(dataList []Data) {
+		if p, ready := update(dataList); ready {
+			ret = append(ret, b.round(p).ToTime(dataList[0].GetTime()))
 		}
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ret, b.PrevState(), nil
+}
 
-		defer b.saveState(state)
-		column := b.cachedArguments[0].(string)
-		v, err := dataList[0].ValueAt(ColumnName(column))
-		if err != nil {
-			return nil, err
+// ComputeVectorBatch is ComputeBatch for the indicator of a VECTOR asset.
+func (b *IndicatorDataBuilder) ComputeVectorBatch(list DataList) (VectorTimeArray, []byte, error) {
+	indicator, err := b.vectorIndicator()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ret := make(VectorTimeArray, 0, list.Len())
+	err = b.batch([]DataList{list}, func(dataList []Data) {
+		if values, ready := indicator.UpdateVector(dataList[0]); ready {
+			ret = append(ret, b.roundVector(values).ToTime(dataList[0].GetTime()))
 		}
-		switch b.assetType {
-		case Asset.SMA:
-			p = state.buildSMA(v, int(b.cachedArguments[1].(int64)))
-		case Asset.EMA:
-			p = state.buildEMA(v, int(b.cachedArguments[1].(int64)))
-		case Asset.WMA:
-			p = state.buildWMA(v, int(b.cachedArguments[1].(int64)))
-		case Asset.HMA:
-			p = state.buildHMA(v, int(b.cachedArguments[1].(int64)))
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return ret, b.PrevState(), nil
+}
+
+// batch calls update with every time-aligned row of lists and saves the state at the end.
+// Checkpoints are recorded along the way.
+func (b *IndicatorDataBuilder) batch(lists []DataList, update func(dataList []Data)) error {
+	var rows [][]Data
+	if len(lists) == 1 {
+		// a single series is already aligned
+		rows = lo.Map(lists[0].Map(), func(data Data, _ int) []Data {
+			return []Data{data}
+		})
+	} else {
+		rows = AlignDataLists(lists...)
+	}
+
+	checked := false
+	for _, dataList := range rows {
+		// every value of a list has the same type, columns are checked once
+		if !checked {
+			if err := b.checkColumns(dataList); err != nil {
+				return err
+			}
+			checked = true
 		}
+		update(dataList)
+
+		if t := dataList[0].GetTime(); b.isCheckpointDue(t) {
+			state, err := b.indicator.MarshalState()
+			if err != nil {
+				return err
+			}
+			b.addCheckpoint(t, state)
+		}
+	}
+	return b.saveState()
+}
+
+// pointUpdate returns the update function of the indicator of a POINT asset, taking one value per dependency.
+func (b *IndicatorDataBuilder) pointUpdate() (func(dataList []Data) (Point, bool), error) {
+	if b.initErr != nil {
+		return nil, b.initErr
+	}
+	switch indicator := b.indicator.(type) {
+	case MultiPointIndicator:
+		return indicator.UpdateAll, nil
+	case PointIndicator:
+		return func(dataList []Data) (Point, bool) {
+			return indicator.Update(dataList[0])
+		}, nil
 	}
+	return nil, fmt.Errorf("asset type %s does not compute points", b.assetType)
+}
 
-	if p == nil {
-		return nil, errors.New("not implemented")
+func (b *IndicatorDataBuilder) vectorIndicator() (VectorIndicator, error) {
+	if b.initErr != nil {
+		return nil, b.initErr
+	}
+	indicator, ok := b.indicator.(VectorIndicator)
+	if !ok {
+		return nil, fmt.Errorf("asset type %s does not compute vectors", b.assetType)
 	}
+	return indicator, nil
+}
 
+func (b *IndicatorDataBuilder) roundVector(values []float64) Vector {
 	if b.Precision >= 0 {
-		p.Value = Math.RoundFloat(p.Value, uint(b.Precision))
+		for i, v := range values {
+			values[i] = Math.RoundFloat(v, uint(b.Precision))
+		}
 	}
+	return NewVector(b.outputColumns, values)
+}
 
-	return p, nil
+// columnValue returns the value of data at column, columns are checked by the builder before any update.
+func columnValue(data Data, column ColumnName) float64 {
+	v, _ := data.ValueAt(column)
+	return v
 }
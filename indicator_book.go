@@ -0,0 +1,188 @@
+package pcommon
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/samber/lo"
+)
+
+/*
+	Liquidity indicators over the book depth assets of a set: the depth within N% below (bid side, book_depth(-N))
+	and above (ask side, book_depth(N)) the market price, given as book_depth addresses or their legacy aliases (bd-mN, bd-pN).
+*/
+
+// bookDepthAssetTypes are the parametric book depth asset type and its legacy aliases.
+var bookDepthAssetTypes = []AssetType{
+	Asset.BOOK_DEPTH,
+	Asset.BOOK_DEPTH_M1, Asset.BOOK_DEPTH_M2, Asset.BOOK_DEPTH_M3, Asset.BOOK_DEPTH_M4, Asset.BOOK_DEPTH_M5,
+	Asset.BOOK_DEPTH_P1, Asset.BOOK_DEPTH_P2, Asset.BOOK_DEPTH_P3, Asset.BOOK_DEPTH_P4, Asset.BOOK_DEPTH_P5,
+}
+
+// the number of bands of a side of the book, at 1 to 5% of the market price
+const bookSideBands = 5
+
+// bookDepthDependency accepts a book depth asset, read through the column argument.
+func bookDepthDependency() DependencyConstraint {
+	return DependencyConstraint{
+		DataTypes:      []DataType{UNIT},
+		AssetTypes:     bookDepthAssetTypes,
+		ColumnArgument: "column",
+	}
+}
+
+// bookBandDependencies are the bid and ask sides of a band (see checkBookBand).
+func bookBandDependencies() []DependencyConstraint {
+	return []DependencyConstraint{bookDepthDependency(), bookDepthDependency()}
+}
+
+// bookSideDependencies are the five bands of a side, from the closest to the market price (see checkBookSide).
+func bookSideDependencies() []DependencyConstraint {
+	return lo.Times(bookSideBands, func(_ int) DependencyConstraint {
+		return bookDepthDependency()
+	})
+}
+
+// checkBookBand returns an error if the dependencies are not the bid and ask sides at the same distance from the market price.
+func checkBookBand(dependencies []AssetAddressParsed) error {
+	bid, err := dependencies[0].BookDepthPercentage()
+	if err != nil {
+		return err
+	}
+	ask, err := dependencies[1].BookDepthPercentage()
+	if err != nil {
+		return err
+	}
+	if bid > 0 || ask < 0 {
+		return errors.New("the first band must be on the bid side and the second one on the ask side")
+	}
+	if bid != -ask {
+		return fmt.Errorf("bid band %g%% and ask band %g%% are different", -bid, ask)
+	}
+	return nil
+}
+
+// checkBookSide returns an error if the dependencies are not the bands at 1 to 5% of the same side of the book.
+func checkBookSide(dependencies []AssetAddressParsed) error {
+	sign := 1.0
+	for i, dep := range dependencies {
+		percentage, err := dep.BookDepthPercentage()
+		if err != nil {
+			return err
+		}
+		if i == 0 && percentage < 0 {
+			sign = -1
+		}
+		if percentage != sign*float64(i+1) {
+			return fmt.Errorf("band %d must be at %g%% of the market price, got %g%%", i, sign*float64(i+1), percentage)
+		}
+	}
+	return nil
+}
+
+/* DEPTH IMBALANCE */
+type bookImbalanceIndicator struct {
+	statelessIndicator
+	column ColumnName
+}
+
+func (ind *bookImbalanceIndicator) Init(args IndicatorArguments) error {
+	ind.column = args.Column("column")
+	return nil
+}
+
+// UpdateAll returns the share of the bid side in the depth of the band, from -1 (only asks) to 1 (only bids).
+func (ind *bookImbalanceIndicator) UpdateAll(dataList []Data) (Point, bool) {
+	bid := columnValue(dataList[0], ind.column)
+	ask := columnValue(dataList[1], ind.column)
+	if bid+ask <= 0 {
+		return Point{}, false
+	}
+	return Point{(bid - ask) / (bid + ask)}, true
+}
+
+/* CUMULATIVE DEPTH */
+type bookCumulativeDepthIndicator struct {
+	statelessIndicator
+	column ColumnName
+}
+
+func (ind *bookCumulativeDepthIndicator) Init(args IndicatorArguments) error {
+	ind.column = args.Column("column")
+	return nil
+}
+
+func (ind *bookCumulativeDepthIndicator) UpdateAll(dataList []Data) (Point, bool) {
+	return Point{columnValue(dataList[0], ind.column) + columnValue(dataList[1], ind.column)}, true
+}
+
+/* LIQUIDITY SLOPE */
+type bookSlopeIndicator struct {
+	statelessIndicator
+	column ColumnName
+}
+
+func (ind *bookSlopeIndicator) Init(args IndicatorArguments) error {
+	ind.column = args.Column("column")
+	return nil
+}
+
+// UpdateAll returns the slope of the least squares line of the depth against the distance of the bands (1 to 5%):
+// the depth added by each percent away from the market price.
+func (ind *bookSlopeIndicator) UpdateAll(dataList []Data) (Point, bool) {
+	n := float64(len(dataList))
+	meanX := (n + 1) / 2
+	meanY := 0.0
+	for _, data := range dataList {
+		meanY += columnValue(data, ind.column)
+	}
+	meanY /= n
+
+	covariance, variance := 0.0, 0.0
+	for i, data := range dataList {
+		dx := float64(i+1) - meanX
+		covariance += dx * (columnValue(data, ind.column) - meanY)
+		variance += dx * dx
+	}
+	return Point{covariance / variance}, true
+}
+
+/* DEPTH CHANGE RATE */
+type bookChangeIndicator struct {
+	maIndicator
+	// the last period values, the oldest one being the reference of the change
+	state ringBuffer
+}
+
+func (ind *bookChangeIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.state = newRingBuffer(ind.period)
+	return nil
+}
+
+// Update returns the change of the depth in percent since period values.
+func (ind *bookChangeIndicator) Update(data Data) (Point, bool) {
+	value := columnValue(data, ind.column)
+	full := ind.state.Full()
+	old := ind.state.Push(value)
+	if !full || old == 0 {
+		return Point{}, false
+	}
+	return Point{100 * (value - old) / old}, true
+}
+
+func (ind *bookChangeIndicator) WarmupPeriod() int {
+	return ind.period + 1
+}
+
+func (ind *bookChangeIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	e.Ring(ind.state)
+	return e.Bytes(), nil
+}
+
+func (ind *bookChangeIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	d.Ring(&ind.state)
+	return d.Err()
+}
@@ -0,0 +1,353 @@
+package pcommon
+
+import "math"
+
+/*
+	Indicators reading the whole candle (high, low and close) of a UNIT dependency.
+	Empty candles (Count == 0) are skipped: they do not update the states.
+*/
+
+// candleDependency accepts a UNIT dependency, read through its high, low and close.
+func candleDependency() DependencyConstraint {
+	return DependencyConstraint{
+		DataTypes: []DataType{UNIT},
+		Columns:   []ColumnName{ColumnType.HIGH, ColumnType.LOW, ColumnType.CLOSE},
+	}
+}
+
+// candleOf returns the unit of data, ok is false if data is not a unit or is an empty candle.
+func candleOf(data Data) (Unit, bool) {
+	var u Unit
+	switch v := data.(type) {
+	case UnitTime:
+		u = v.Unit
+	case *UnitTime:
+		u = v.Unit
+	default:
+		return Unit{}, false
+	}
+	return u, !u.IsEmpty()
+}
+
+/* TRUE RANGE : TR */
+type trState struct {
+	PrevClose float64
+	HasPrev   bool
+}
+
+// build returns the greatest of the candle range and the gaps from the previous close.
+func (state *trState) build(u Unit) float64 {
+	tr := u.High - u.Low
+	if state.HasPrev {
+		tr = math.Max(tr, math.Max(math.Abs(u.High-state.PrevClose), math.Abs(u.Low-state.PrevClose)))
+	}
+	state.PrevClose = u.Close
+	state.HasPrev = true
+	return tr
+}
+
+func (state *trState) encode(e *stateEncoder) {
+	e.Float(state.PrevClose)
+	e.Bool(state.HasPrev)
+}
+
+func (state *trState) decode(d *stateDecoder) {
+	state.PrevClose = d.Float()
+	state.HasPrev = d.Bool()
+}
+
+type trIndicator struct {
+	state trState
+}
+
+func (ind *trIndicator) Init(args IndicatorArguments) error {
+	return nil
+}
+
+func (ind *trIndicator) Update(data Data) (Point, bool) {
+	u, ok := candleOf(data)
+	if !ok {
+		return Point{}, false
+	}
+	return Point{ind.state.build(u)}, true
+}
+
+func (ind *trIndicator) WarmupPeriod() int {
+	return 1
+}
+
+func (ind *trIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *trIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+/* AVERAGE TRUE RANGE : ATR */
+type atrState struct {
+	TR  trState
+	ATR float64
+	Pos int64
+}
+
+// build smooths the true range like buildRSI smooths the gains and losses (Wilder):
+// the first ATR is the average of the first true ranges.
+func (state *atrState) build(u Unit, period int64) (Point, bool) {
+	tr := state.TR.build(u)
+	state.Pos++
+
+	if state.Pos <= period {
+		state.ATR += tr
+		if state.Pos < period {
+			return Point{}, false
+		}
+		state.ATR /= float64(period)
+	} else {
+		state.ATR = (state.ATR*float64(period-1) + tr) / float64(period)
+	}
+	return Point{state.ATR}, true
+}
+
+func (state *atrState) encode(e *stateEncoder) {
+	state.TR.encode(e)
+	e.Float(state.ATR)
+	e.Int(state.Pos)
+}
+
+func (state *atrState) decode(d *stateDecoder) {
+	state.TR.decode(d)
+	state.ATR = d.Float()
+	state.Pos = d.Int()
+}
+
+type atrIndicator struct {
+	period int64
+	state  atrState
+}
+
+func (ind *atrIndicator) Init(args IndicatorArguments) error {
+	ind.period = args.Int("period")
+	return nil
+}
+
+func (ind *atrIndicator) Update(data Data) (Point, bool) {
+	u, ok := candleOf(data)
+	if !ok {
+		return Point{}, false
+	}
+	return ind.state.build(u, ind.period)
+}
+
+func (ind *atrIndicator) WarmupPeriod() int {
+	return int(ind.period)
+}
+
+func (ind *atrIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *atrIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+/* HIGHEST HIGH AND LOWEST LOW, shared by the stochastic oscillator and Williams %R */
+type highLowState struct {
+	High monotonicDeque
+	Low  monotonicDeque
+}
+
+func newHighLowState(period int) highLowState {
+	return highLowState{
+		High: newMonotonicDeque(period, true),
+		Low:  newMonotonicDeque(period, false),
+	}
+}
+
+// build returns the highest high and the lowest low of the window.
+func (state *highLowState) build(u Unit) (float64, float64, bool) {
+	highest := state.High.Push(u.High)
+	lowest := state.Low.Push(u.Low)
+	return highest, lowest, state.High.Full()
+}
+
+func (state *highLowState) encode(e *stateEncoder) {
+	e.Deque(state.High)
+	e.Deque(state.Low)
+}
+
+func (state *highLowState) decode(d *stateDecoder) {
+	d.Deque(&state.High)
+	d.Deque(&state.Low)
+}
+
+/* STOCHASTIC OSCILLATOR */
+
+var stochasticColumns = []ColumnName{"k", "d"}
+
+type stochasticState struct {
+	HighLow highLowState
+	// %D is the SMA of %K
+	D smaState
+}
+
+type stochasticIndicator struct {
+	kPeriod int
+	dPeriod int
+	state   stochasticState
+}
+
+func (ind *stochasticIndicator) Init(args IndicatorArguments) error {
+	ind.kPeriod = int(args.Int("k_period"))
+	ind.dPeriod = int(args.Int("d_period"))
+	ind.state = stochasticState{
+		HighLow: newHighLowState(ind.kPeriod),
+		D:       newSMAState(ind.dPeriod),
+	}
+	return nil
+}
+
+func (ind *stochasticIndicator) UpdateVector(data Data) ([]float64, bool) {
+	u, ok := candleOf(data)
+	if !ok {
+		return nil, false
+	}
+	highest, lowest, ready := ind.state.HighLow.build(u)
+	if !ready {
+		return nil, false
+	}
+
+	// a flat window has no range, the close is considered in the middle of it
+	k := 50.0
+	if highest != lowest {
+		k = 100 * (u.Close - lowest) / (highest - lowest)
+	}
+	d, ready := ind.state.D.build(k)
+	if !ready {
+		return nil, false
+	}
+	return []float64{k, d.Value}, true
+}
+
+func (ind *stochasticIndicator) WarmupPeriod() int {
+	return ind.kPeriod + ind.dPeriod - 1
+}
+
+func (ind *stochasticIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.HighLow.encode(e)
+	ind.state.D.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *stochasticIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.HighLow.decode(d)
+	ind.state.D.decode(d)
+	return d.Err()
+}
+
+/* WILLIAMS %R */
+type williamsRIndicator struct {
+	period int
+	state  highLowState
+}
+
+func (ind *williamsRIndicator) Init(args IndicatorArguments) error {
+	ind.period = int(args.Int("period"))
+	ind.state = newHighLowState(ind.period)
+	return nil
+}
+
+func (ind *williamsRIndicator) Update(data Data) (Point, bool) {
+	u, ok := candleOf(data)
+	if !ok {
+		return Point{}, false
+	}
+	highest, lowest, ready := ind.state.build(u)
+	if !ready {
+		return Point{}, false
+	}
+	if highest == lowest {
+		return Point{-50}, true
+	}
+	return Point{-100 * (highest - u.Close) / (highest - lowest)}, true
+}
+
+func (ind *williamsRIndicator) WarmupPeriod() int {
+	return ind.period
+}
+
+func (ind *williamsRIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *williamsRIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+/* COMMODITY CHANNEL INDEX : CCI */
+const cciConstant = 0.015
+
+type cciIndicator struct {
+	period int
+	// SMA of the typical prices, its buffer holds the window for the mean deviation
+	state smaState
+}
+
+func (ind *cciIndicator) Init(args IndicatorArguments) error {
+	ind.period = int(args.Int("period"))
+	ind.state = newSMAState(ind.period)
+	return nil
+}
+
+// the mean deviation depends on the mean of the window, it is recomputed on every value (O(period))
+func (ind *cciIndicator) Update(data Data) (Point, bool) {
+	u, ok := candleOf(data)
+	if !ok {
+		return Point{}, false
+	}
+	typical := (u.High + u.Low + u.Close) / 3
+	sma, ready := ind.state.build(typical)
+	if !ready {
+		return Point{}, false
+	}
+
+	deviation := 0.0
+	for _, v := range ind.state.Buffer.Values {
+		deviation += math.Abs(v - sma.Value)
+	}
+	deviation /= float64(ind.period)
+	if deviation == 0 {
+		return Point{0}, true
+	}
+	return Point{(typical - sma.Value) / (cciConstant * deviation)}, true
+}
+
+func (ind *cciIndicator) WarmupPeriod() int {
+	return ind.period
+}
+
+func (ind *cciIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *cciIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
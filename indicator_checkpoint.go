@@ -0,0 +1,130 @@
+package pcommon
+
+import (
+	"sort"
+	"time"
+)
+
+/*
+	Checkpoints are states of an indicator recorded while computing, keyed by the time of the value they were recorded after.
+	When late data arrives or an archive day is parsed again, the builder restores the last checkpoint before the first
+	changed time and replays the data from there, instead of recomputing the indicator from the beginning.
+*/
+
+// IndicatorCheckpoint is the state of an indicator once the value at Time is computed.
+type IndicatorCheckpoint struct {
+	Time  TimeUnit `json:"time"`
+	State []byte   `json:"state"`
+}
+
+// CheckpointRetention sets how often checkpoints are recorded and how many are kept.
+type CheckpointRetention struct {
+	// minimum time between two checkpoints, 0 to record one on every value
+	Interval time.Duration
+	// maximum number of checkpoints, the oldest ones are dropped first (0 for no limit)
+	MaxCount int
+}
+
+// SetCheckpointRetention enables the checkpoints of the builder.
+func (b *IndicatorDataBuilder) SetCheckpointRetention(retention CheckpointRetention) {
+	b.retention = &retention
+	b.pruneCheckpoints()
+}
+
+// Checkpoints returns a copy of the checkpoints, sorted by time.
+func (b *IndicatorDataBuilder) Checkpoints() []IndicatorCheckpoint {
+	ret := make([]IndicatorCheckpoint, len(b.checkpoints))
+	for i, c := range b.checkpoints {
+		ret[i] = IndicatorCheckpoint{Time: c.Time, State: append([]byte{}, c.State...)}
+	}
+	return ret
+}
+
+// LoadCheckpoints replaces the checkpoints of the builder, ex: with the ones stored along with the indicator's data.
+func (b *IndicatorDataBuilder) LoadCheckpoints(checkpoints []IndicatorCheckpoint) {
+	b.checkpoints = make([]IndicatorCheckpoint, len(checkpoints))
+	for i, c := range checkpoints {
+		b.checkpoints[i] = IndicatorCheckpoint{Time: c.Time, State: append([]byte{}, c.State...)}
+	}
+	sort.Slice(b.checkpoints, func(i, j int) bool {
+		return b.checkpoints[i].Time < b.checkpoints[j].Time
+	})
+	b.pruneCheckpoints()
+}
+
+// RestoreBefore loads the state of the last checkpoint strictly before t and returns its time:
+// the values after this time have to be computed again (see ReplayFrom).
+// If there is no checkpoint before t, the initial state is loaded and -1 is returned: every value has to be computed again.
+// The later checkpoints are dropped, they are recorded again by the replay.
+func (b *IndicatorDataBuilder) RestoreBefore(t TimeUnit) (TimeUnit, error) {
+	i := sort.Search(len(b.checkpoints), func(i int) bool {
+		return b.checkpoints[i].Time >= t
+	}) - 1
+
+	checkpoint := IndicatorCheckpoint{Time: -1}
+	if i >= 0 {
+		checkpoint = b.checkpoints[i]
+	}
+	b.checkpoints = b.checkpoints[:i+1]
+	b.prevState = append([]byte(nil), checkpoint.State...)
+	b.initErr = b.init()
+	if b.initErr != nil {
+		return 0, b.initErr
+	}
+	return checkpoint.Time, nil
+}
+
+// ReplayFrom restores the last checkpoint before t and computes the values of lists after it with ComputeBatchAll.
+// lists can start before the checkpoint, the values already computed are skipped.
+func (b *IndicatorDataBuilder) ReplayFrom(t TimeUnit, lists ...DataList) (PointTimeArray, []byte, error) {
+	from, err := b.RestoreBefore(t)
+	if err != nil {
+		return nil, nil, err
+	}
+	remaining := make([]DataList, len(lists))
+	for i, list := range lists {
+		remaining[i] = removeUntil(list, from)
+	}
+	return b.ComputeBatchAll(remaining...)
+}
+
+// ReplayVectorFrom is ReplayFrom for the indicator of a VECTOR asset.
+func (b *IndicatorDataBuilder) ReplayVectorFrom(t TimeUnit, list DataList) (VectorTimeArray, []byte, error) {
+	from, err := b.RestoreBefore(t)
+	if err != nil {
+		return nil, nil, err
+	}
+	return b.ComputeVectorBatch(removeUntil(list, from))
+}
+
+// removeUntil removes the values of the sorted list at or before t.
+func removeUntil(list DataList, t TimeUnit) DataList {
+	data := list.Map()
+	n := sort.Search(len(data), func(i int) bool {
+		return data[i].GetTime() > t
+	})
+	return list.RemoveFirstN(n)
+}
+
+func (b *IndicatorDataBuilder) isCheckpointDue(t TimeUnit) bool {
+	if b.retention == nil {
+		return false
+	}
+	if len(b.checkpoints) == 0 {
+		return true
+	}
+	last := b.checkpoints[len(b.checkpoints)-1].Time
+	return t > last && t >= last.Add(b.retention.Interval)
+}
+
+// addCheckpoint records state at t, state must not be modified afterwards.
+func (b *IndicatorDataBuilder) addCheckpoint(t TimeUnit, state []byte) {
+	b.checkpoints = append(b.checkpoints, IndicatorCheckpoint{Time: t, State: state})
+	b.pruneCheckpoints()
+}
+
+func (b *IndicatorDataBuilder) pruneCheckpoints() {
+	if b.retention != nil && b.retention.MaxCount > 0 && len(b.checkpoints) > b.retention.MaxCount {
+		b.checkpoints = append([]IndicatorCheckpoint{}, b.checkpoints[len(b.checkpoints)-b.retention.MaxCount:]...)
+	}
+}
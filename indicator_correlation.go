@@ -0,0 +1,257 @@
+package pcommon
+
+import (
+	"errors"
+	"math"
+)
+
+/*
+	Statistics between two dependencies over a rolling window: correlation, covariance and beta.
+	The dependencies may belong to different sets (ex: BTCUSDT and ETHUSDT), their values are aligned on time.
+*/
+
+// pairDependencies are two assets exposing the column argument, the second one may belong to another set.
+func pairDependencies() []DependencyConstraint {
+	return []DependencyConstraint{columnDependency("column"), columnDependency("column")}
+}
+
+func pairArguments() []ArgumentSchema {
+	return []ArgumentSchema{
+		columnArgument(ColumnType.CLOSE),
+		periodArgument(30, 2),
+		{
+			Name:        "returns",
+			Type:        ARG_BOOL,
+			Default:     "true",
+			Description: "If true, the statistic is computed on the relative changes of the values instead of the values.",
+		},
+	}
+}
+
+// covarianceState keeps the means, the sums of squared deviations (M2) and the co-moment of the window
+// with Welford's algorithm, like stddevState.
+type covarianceState struct {
+	A ringBuffer
+	B ringBuffer
+
+	MeanA float64
+	MeanB float64
+	M2A   float64
+	M2B   float64
+	C     float64
+
+	// previous values, when the statistics are computed on the returns
+	PrevA   float64
+	PrevB   float64
+	HasPrev bool
+}
+
+func newCovarianceState(period int) covarianceState {
+	return covarianceState{A: newRingBuffer(period), B: newRingBuffer(period)}
+}
+
+// returns converts the values into their relative change since the previous ones, ok is false for the first values.
+func (state *covarianceState) returns(a, b float64) (float64, float64, bool) {
+	defer func() {
+		state.PrevA, state.PrevB = a, b
+		state.HasPrev = true
+	}()
+	if !state.HasPrev || state.PrevA == 0 || state.PrevB == 0 {
+		return 0, 0, false
+	}
+	return a/state.PrevA - 1, b/state.PrevB - 1, true
+}
+
+// push adds a pair of values and returns true once the window is full.
+func (state *covarianceState) push(a, b float64) bool {
+	if state.A.Full() {
+		oldA := state.A.At(0)
+		oldB := state.B.At(0)
+		state.remove(oldA, oldB, float64(len(state.A.Values)))
+	}
+	state.A.Push(a)
+	state.B.Push(b)
+	state.add(a, b, float64(state.A.Len()))
+
+	// the sliding updates accumulate rounding errors, the window is recomputed once per period (amortized O(1))
+	if state.A.Count%len(state.A.Values) == 0 {
+		state.resync()
+	}
+	return state.A.Full()
+}
+
+// add updates the moments with a new pair, n being the number of pairs once added.
+func (state *covarianceState) add(a, b, n float64) {
+	deltaA := a - state.MeanA
+	deltaB := b - state.MeanB
+	state.MeanA += deltaA / n
+	state.MeanB += deltaB / n
+	state.M2A += deltaA * (a - state.MeanA)
+	state.M2B += deltaB * (b - state.MeanB)
+	state.C += deltaA * (b - state.MeanB)
+}
+
+// remove updates the moments without an old pair, n being the number of pairs before removing it.
+func (state *covarianceState) remove(a, b, n float64) {
+	if n <= 1 {
+		state.MeanA, state.MeanB, state.M2A, state.M2B, state.C = 0, 0, 0, 0, 0
+		return
+	}
+	prevMeanB := state.MeanB
+	state.MeanA -= (a - state.MeanA) / (n - 1)
+	state.MeanB -= (b - state.MeanB) / (n - 1)
+	state.M2A -= (a - state.MeanA) * (a - state.MeanA) * (n - 1) / n
+	state.M2B -= (b - state.MeanB) * (b - state.MeanB) * (n - 1) / n
+	state.C -= (a - state.MeanA) * (b - prevMeanB)
+}
+
+// resync recomputes the moments of the full window with two passes.
+func (state *covarianceState) resync() {
+	n := float64(len(state.A.Values))
+	state.MeanA, state.MeanB = 0, 0
+	for i := range state.A.Values {
+		state.MeanA += state.A.Values[i]
+		state.MeanB += state.B.Values[i]
+	}
+	state.MeanA /= n
+	state.MeanB /= n
+
+	state.M2A, state.M2B, state.C = 0, 0, 0
+	for i := range state.A.Values {
+		deltaA := state.A.Values[i] - state.MeanA
+		deltaB := state.B.Values[i] - state.MeanB
+		state.M2A += deltaA * deltaA
+		state.M2B += deltaB * deltaB
+		state.C += deltaA * deltaB
+	}
+}
+
+func (state *covarianceState) encode(e *stateEncoder) {
+	e.Ring(state.A)
+	e.Ring(state.B)
+	e.Float(state.MeanA)
+	e.Float(state.MeanB)
+	e.Float(state.M2A)
+	e.Float(state.M2B)
+	e.Float(state.C)
+	e.Float(state.PrevA)
+	e.Float(state.PrevB)
+	e.Bool(state.HasPrev)
+}
+
+func (state *covarianceState) decode(d *stateDecoder) {
+	d.Ring(&state.A)
+	d.Ring(&state.B)
+	state.MeanA = d.Float()
+	state.MeanB = d.Float()
+	state.M2A = d.Float()
+	state.M2B = d.Float()
+	state.C = d.Float()
+	state.PrevA = d.Float()
+	state.PrevB = d.Float()
+	state.HasPrev = d.Bool()
+	if d.err != nil {
+		return
+	}
+
+	// the pairs are pushed together, and the moments must be the ones of the window
+	if state.A.Count != state.B.Count {
+		d.fail(errors.New("inconsistent pairs in indicator state"))
+		return
+	}
+	n := state.A.Len()
+	sumA, absA := state.A.sum()
+	sumB, absB := state.B.sum()
+	meanA, meanB := 0.0, 0.0
+	if n > 0 {
+		meanA, meanB = sumA/float64(n), sumB/float64(n)
+		absA, absB = absA/float64(n), absB/float64(n)
+	}
+	m2A, m2B, c, squaresA, squaresB := 0.0, 0.0, 0.0, 0.0, 0.0
+	for i := 0; i < n; i++ {
+		a, b := state.A.At(i), state.B.At(i)
+		m2A += (a - meanA) * (a - meanA)
+		m2B += (b - meanB) * (b - meanB)
+		c += (a - meanA) * (b - meanB)
+		squaresA += a * a
+		squaresB += b * b
+	}
+	d.checkDerived("mean", state.MeanA, meanA, absA)
+	d.checkDerived("mean", state.MeanB, meanB, absB)
+	d.checkDerived("M2", state.M2A, m2A, squaresA)
+	d.checkDerived("M2", state.M2B, m2B, squaresB)
+	d.checkDerived("co-moment", state.C, c, math.Sqrt(squaresA*squaresB))
+}
+
+type pairStatistic int
+
+const (
+	pairCorrelation pairStatistic = iota
+	pairCovariance
+	pairBeta
+)
+
+// pairIndicator computes a statistic of the column of its first dependency against the one of its second dependency.
+type pairIndicator struct {
+	statistic pairStatistic
+	column    ColumnName
+	period    int
+	returns   bool
+	state     covarianceState
+}
+
+func (ind *pairIndicator) Init(args IndicatorArguments) error {
+	ind.column = args.Column("column")
+	ind.period = int(args.Int("period"))
+	ind.returns = args.Bool("returns")
+	ind.state = newCovarianceState(ind.period)
+	return nil
+}
+
+func (ind *pairIndicator) UpdateAll(dataList []Data) (Point, bool) {
+	a := columnValue(dataList[0], ind.column)
+	b := columnValue(dataList[1], ind.column)
+	if ind.returns {
+		var ok bool
+		if a, b, ok = ind.state.returns(a, b); !ok {
+			return Point{}, false
+		}
+	}
+	if !ind.state.push(a, b) {
+		return Point{}, false
+	}
+
+	state := ind.state
+	switch ind.statistic {
+	case pairCorrelation:
+		if state.M2A <= 0 || state.M2B <= 0 {
+			return Point{0}, true
+		}
+		return Point{state.C / math.Sqrt(state.M2A*state.M2B)}, true
+	case pairBeta:
+		if state.M2B <= 0 {
+			return Point{0}, true
+		}
+		return Point{state.C / state.M2B}, true
+	}
+	return Point{state.C / float64(ind.period)}, true
+}
+
+func (ind *pairIndicator) WarmupPeriod() int {
+	if ind.returns {
+		return ind.period + 1
+	}
+	return ind.period
+}
+
+func (ind *pairIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *pairIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
@@ -0,0 +1,241 @@
+package pcommon
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+/*
+	FORMULA computes an arithmetic expression over the columns of its dependencies, at the times they share.
+
+	A dependency column is referenced as $<dependency index>.<column>, ex:
+		futures basis:       $0.close - $1.close
+		annualized basis:    ($0.close - $1.close) / $1.close * 365 / 90
+		book imbalance:      ($0.value - $1.value) / ($0.value + $1.value)
+
+	The expression supports numbers, + - * /, unary minus and parentheses.
+	Every dependency must be referenced, and no point is computed when the result is not finite (ex: a division by zero).
+*/
+
+const formulaMaxDependencies = 16
+
+type formulaExpression func(dataList []Data) float64
+
+// formulaParser is a recursive descent parser of the expression of a formula:
+//
+//	expression = term { ("+" | "-") term }
+//	term       = unary { ("*" | "/") unary }
+//	unary      = "-" unary | primary
+//	primary    = number | reference | "(" expression ")"
+type formulaParser struct {
+	input string
+	pos   int
+	// columns referenced for each dependency
+	columns [][]ColumnName
+}
+
+// parseFormula compiles the expression and returns the columns it reads from each dependency.
+func parseFormula(input string) (formulaExpression, [][]ColumnName, error) {
+	p := &formulaParser{input: strings.Join(strings.Fields(input), "")}
+	if p.input == "" {
+		return nil, nil, errors.New("expression is empty")
+	}
+
+	expr, err := p.expression()
+	if err != nil {
+		return nil, nil, err
+	}
+	if p.pos < len(p.input) {
+		return nil, nil, p.unexpected()
+	}
+	if len(p.columns) == 0 {
+		return nil, nil, errors.New("expression does not reference any dependency")
+	}
+	for i, columns := range p.columns {
+		if len(columns) == 0 {
+			return nil, nil, fmt.Errorf("dependency $%d is not referenced in the expression", i)
+		}
+	}
+	return expr, p.columns, nil
+}
+
+func (p *formulaParser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+func (p *formulaParser) unexpected() error {
+	if p.pos >= len(p.input) {
+		return errors.New("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected character %q at position %d of expression", p.input[p.pos], p.pos)
+}
+
+func (p *formulaParser) expression() (formulaExpression, error) {
+	left, err := p.term()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == '+' || p.peek() == '-' {
+		op := p.peek()
+		p.pos++
+		right, err := p.term()
+		if err != nil {
+			return nil, err
+		}
+		a, b := left, right
+		if op == '+' {
+			left = func(dataList []Data) float64 { return a(dataList) + b(dataList) }
+		} else {
+			left = func(dataList []Data) float64 { return a(dataList) - b(dataList) }
+		}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) term() (formulaExpression, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == '*' || p.peek() == '/' {
+		op := p.peek()
+		p.pos++
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		a, b := left, right
+		if op == '*' {
+			left = func(dataList []Data) float64 { return a(dataList) * b(dataList) }
+		} else {
+			left = func(dataList []Data) float64 { return a(dataList) / b(dataList) }
+		}
+	}
+	return left, nil
+}
+
+func (p *formulaParser) unary() (formulaExpression, error) {
+	if p.peek() != '-' {
+		return p.primary()
+	}
+	p.pos++
+	operand, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return func(dataList []Data) float64 { return -operand(dataList) }, nil
+}
+
+func (p *formulaParser) primary() (formulaExpression, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.pos++
+		expr, err := p.expression()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ')' {
+			return nil, errors.New("missing closing parenthesis in expression")
+		}
+		p.pos++
+		return expr, nil
+	case c == '$':
+		return p.reference()
+	case isFormulaDigit(c) || c == '.':
+		return p.number()
+	}
+	return nil, p.unexpected()
+}
+
+func (p *formulaParser) number() (formulaExpression, error) {
+	start := p.pos
+	for isFormulaDigit(p.peek()) || p.peek() == '.' {
+		p.pos++
+	}
+	v, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %s in expression", p.input[start:p.pos])
+	}
+	return func(dataList []Data) float64 { return v }, nil
+}
+
+// reference parses $<dependency index>.<column>
+func (p *formulaParser) reference() (formulaExpression, error) {
+	start := p.pos
+	p.pos++
+	for isFormulaDigit(p.peek()) {
+		p.pos++
+	}
+	index, err := strconv.Atoi(p.input[start+1 : p.pos])
+	if err != nil {
+		return nil, fmt.Errorf("missing dependency index at position %d of expression", start)
+	}
+	if index >= formulaMaxDependencies {
+		return nil, fmt.Errorf("dependency $%d is out of range, a formula has at most %d dependencies", index, formulaMaxDependencies)
+	}
+	if p.peek() != '.' {
+		return nil, fmt.Errorf("missing column of dependency $%d in expression", index)
+	}
+	p.pos++
+
+	columnStart := p.pos
+	for isFormulaColumnChar(p.peek()) {
+		p.pos++
+	}
+	column := ColumnName(p.input[columnStart:p.pos])
+	if column == "" {
+		return nil, fmt.Errorf("missing column of dependency $%d in expression", index)
+	}
+	if column == ColumnType.TIME {
+		return nil, errors.New("the time column cannot be used in an expression")
+	}
+
+	for len(p.columns) <= index {
+		p.columns = append(p.columns, nil)
+	}
+	if !lo.Contains(p.columns[index], column) {
+		p.columns[index] = append(p.columns[index], column)
+	}
+	return func(dataList []Data) float64 { return columnValue(dataList[index], column) }, nil
+}
+
+func isFormulaDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isFormulaColumnChar(c byte) bool {
+	return isFormulaDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_'
+}
+
+type formulaIndicator struct {
+	statelessIndicator
+	expression formulaExpression
+	columns    [][]ColumnName
+}
+
+func (ind *formulaIndicator) Init(args IndicatorArguments) error {
+	var err error
+	ind.expression, ind.columns, err = parseFormula(args.String("expression"))
+	return err
+}
+
+func (ind *formulaIndicator) DependencyColumns() [][]ColumnName {
+	return ind.columns
+}
+
+func (ind *formulaIndicator) UpdateAll(dataList []Data) (Point, bool) {
+	v := ind.expression(dataList)
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return Point{}, false
+	}
+	return Point{v}, true
+}
@@ -2,111 +2,617 @@ package pcommon
 
 import (
 	"encoding/json"
+	"errors"
 	"math"
 )
 
-type maState struct {
-	Sum      float64
-	Buffer   []float64
-	Pos      int
-	Count    int
-	EMA      float64
-	WMABuff  []float64
-	AltState [][]byte
+/* SIMPLE MOVING AVERAGE : SMA */
+type smaState struct {
+	Sum    float64
+	Buffer ringBuffer
 }
 
-func newEmptyMAState(period int) maState {
-	return maState{
-		Buffer:  make([]float64, period),
-		WMABuff: make([]float64, period),
-	}
+func newSMAState(period int) smaState {
+	return smaState{Buffer: newRingBuffer(period)}
 }
 
-func (state *maState) buildSMA(value float64, SMA_PERIOD int) *Point {
-	state.Count++
-	if state.Count <= SMA_PERIOD {
+func (state *smaState) build(value float64) (Point, bool) {
+	full := state.Buffer.Full()
+	old := state.Buffer.Push(value)
+	if full {
+		// Full buffer, replace the oldest value
+		state.Sum = state.Sum - old + value
+	} else {
 		state.Sum += value
-		state.Buffer[state.Pos] = value
-		state.Pos = (state.Pos + 1) % SMA_PERIOD
+	}
 
-		if state.Count == SMA_PERIOD {
-			return &Point{state.Sum / float64(SMA_PERIOD)}
-		}
-		return &Point{-1}
+	if !state.Buffer.Full() {
+		return Point{}, false
 	}
+	return Point{state.Sum / float64(len(state.Buffer.Values))}, true
+}
+
+func (state *smaState) encode(e *stateEncoder) {
+	e.Float(state.Sum)
+	e.Ring(state.Buffer)
+}
+
+func (state *smaState) decode(d *stateDecoder) {
+	state.Sum = d.Float()
+	d.Ring(&state.Buffer)
+}
 
-	// Full buffer, replace the oldest value
-	state.Sum = state.Sum - state.Buffer[state.Pos] + value
-	state.Buffer[state.Pos] = value
-	state.Pos = (state.Pos + 1) % SMA_PERIOD
-	return &Point{state.Sum / float64(SMA_PERIOD)}
+/* EXPONENTIAL MOVING AVERAGE : EMA */
+type emaState struct {
+	EMA   float64
+	Count int
 }
 
-func (state *maState) buildEMA(value float64, period int) *Point {
+func (state *emaState) build(value float64, period int) (Point, bool) {
 	if state.Count == 0 {
 		state.EMA = value
 		state.Count++
-		return &Point{state.EMA}
+		return Point{state.EMA}, true
 	}
 
 	k := 2.0 / float64(period+1)
 	today := value
 	state.EMA = today*k + state.EMA*(1-k)
 	state.Count++
-	return &Point{state.EMA}
+	return Point{state.EMA}, true
 }
 
-func (state *maState) buildWMA(value float64, WMA_PERIOD int) *Point {
-	state.WMABuff[state.Pos] = value
-	state.Pos = (state.Pos + 1) % WMA_PERIOD
-	state.Count++
+func (state *emaState) encode(e *stateEncoder) {
+	e.Float(state.EMA)
+	e.Int(int64(state.Count))
+}
+
+func (state *emaState) decode(d *stateDecoder) {
+	state.EMA = d.Float()
+	state.Count = int(d.Int())
+}
 
-	if state.Count < WMA_PERIOD {
-		return &Point{-1}
+/* WEIGHTED MOVING AVERAGE : WMA */
+type wmaState struct {
+	Buffer ringBuffer
+}
+
+func newWMAState(period int) wmaState {
+	return wmaState{Buffer: newRingBuffer(period)}
+}
+
+func (state *wmaState) build(value float64) (Point, bool) {
+	state.Buffer.Push(value)
+	if !state.Buffer.Full() {
+		return Point{}, false
 	}
 
 	weightedSum := 0.0
 	weight := 0.0
-	for i := 0; i < WMA_PERIOD; i++ {
+	for i := 0; i < len(state.Buffer.Values); i++ {
 		w := float64(i + 1)
-		weightedSum += state.WMABuff[(state.Pos+i)%WMA_PERIOD] * w
+		weightedSum += state.Buffer.At(i) * w
 		weight += w
 	}
-	return &Point{weightedSum / weight}
+	return Point{weightedSum / weight}, true
 }
 
-func (state *maState) buildHMA(value float64, HMA_PERIOD int) *Point {
-	sqrtPeriod := int(math.Sqrt(float64(HMA_PERIOD)))
+func (state *wmaState) encode(e *stateEncoder) {
+	e.Ring(state.Buffer)
+}
 
-	if len(state.AltState) == 0 {
-		halfState := newEmptyMAState(HMA_PERIOD / 2)
-		fullState := newEmptyMAState(HMA_PERIOD)
-		wmaSqrtState := newEmptyMAState(sqrtPeriod)
+func (state *wmaState) decode(d *stateDecoder) {
+	d.Ring(&state.Buffer)
+}
 
-		hb, _ := json.Marshal(halfState)
-		fb, _ := json.Marshal(fullState)
-		wb, _ := json.Marshal(wmaSqrtState)
-		state.AltState = [][]byte{hb, fb, wb}
-	}
+/* HULL MOVING AVERAGE : HMA */
+type hmaState struct {
+	Half wmaState
+	Full wmaState
+	Sqrt wmaState
+}
 
-	halfMaState, _ := parseIndicatorState[maState](&IndicatorDataBuilder{prevState: state.AltState[0]})
-	fullMaState, _ := parseIndicatorState[maState](&IndicatorDataBuilder{prevState: state.AltState[1]})
+func hmaSqrtPeriod(HMA_PERIOD int) int {
+	return int(math.Sqrt(float64(HMA_PERIOD)))
+}
 
-	wmaHalfPeriod := halfMaState.buildWMA(value, HMA_PERIOD/2)
-	wmaFullPeriod := fullMaState.buildWMA(value, HMA_PERIOD)
+func newHMAState(period int) hmaState {
+	return hmaState{
+		Half: newWMAState(period / 2),
+		Full: newWMAState(period),
+		Sqrt: newWMAState(hmaSqrtPeriod(period)),
+	}
+}
 
-	//serializing the state
-	state.AltState[0], _ = json.Marshal(halfMaState)
-	state.AltState[1], _ = json.Marshal(fullMaState)
+func (state *hmaState) build(value float64) (Point, bool) {
+	wmaHalfPeriod, _ := state.Half.build(value)
+	wmaFullPeriod, _ := state.Full.build(value)
 
-	state.Count++
-	if state.Count < HMA_PERIOD {
-		return &Point{-1}
+	if !state.Full.Buffer.Full() {
+		return Point{}, false
 	}
 
-	wmaSqrtState, _ := parseIndicatorState[maState](&IndicatorDataBuilder{prevState: state.AltState[2]})
 	close := (2 * wmaHalfPeriod.Value) - wmaFullPeriod.Value
-	hmaValue := wmaSqrtState.buildWMA(close, sqrtPeriod)
-	state.AltState[2], _ = json.Marshal(wmaSqrtState)
-	return &Point{hmaValue.Value}
+	return state.Sqrt.build(close)
+}
+
+func (state *hmaState) encode(e *stateEncoder) {
+	state.Half.encode(e)
+	state.Full.encode(e)
+	state.Sqrt.encode(e)
+}
+
+func (state *hmaState) decode(d *stateDecoder) {
+	state.Half.decode(d)
+	state.Full.decode(d)
+	state.Sqrt.decode(d)
+}
+
+// legacyMAState is the JSON state the moving averages were saved with before the binary format.
+type legacyMAState struct {
+	Sum      float64
+	Buffer   []float64
+	Pos      int
+	Count    int
+	EMA      float64
+	WMABuff  []float64
+	AltState [][]byte
+}
+
+func parseLegacyMAState(state []byte) (legacyMAState, error) {
+	var legacy legacyMAState
+	err := json.Unmarshal(state, &legacy)
+	return legacy, err
+}
+
+// wma converts the state of a WMA over period values, or of one of the WMAs of an HMA
+func (legacy legacyMAState) wma(period int) (wmaState, error) {
+	buffer := ringBuffer{Values: legacy.WMABuff, Pos: legacy.Pos, Count: legacy.Count}
+	return wmaState{Buffer: buffer}, buffer.check(period)
+}
+
+// hma converts the JSON encoded WMA states HMA kept in AltState into the state sized by the indicator
+func (legacy legacyMAState) hma(current hmaState) (hmaState, error) {
+	subStates := []*wmaState{&current.Half, &current.Full, &current.Sqrt}
+	for i, alt := range legacy.AltState {
+		sub, err := parseLegacyMAState(alt)
+		if err != nil {
+			return hmaState{}, err
+		}
+		if *subStates[i], err = sub.wma(len(subStates[i].Buffer.Values)); err != nil {
+			return hmaState{}, err
+		}
+	}
+	return current, nil
+}
+
+// maIndicator holds what the moving averages computed on a column over a period share.
+type maIndicator struct {
+	column ColumnName
+	period int
+}
+
+func (ind *maIndicator) Init(args IndicatorArguments) error {
+	ind.column = args.Column("column")
+	ind.period = int(args.Int("period"))
+	return nil
+}
+
+type smaIndicator struct {
+	maIndicator
+	state smaState
+}
+
+func (ind *smaIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.state = newSMAState(ind.period)
+	return nil
+}
+
+func (ind *smaIndicator) Update(data Data) (Point, bool) {
+	return ind.state.build(columnValue(data, ind.column))
+}
+
+func (ind *smaIndicator) WarmupPeriod() int {
+	return ind.period
+}
+
+func (ind *smaIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *smaIndicator) UnmarshalState(state []byte) error {
+	if isJSONIndicatorState(state) {
+		legacy, err := parseLegacyMAState(state)
+		if err != nil {
+			return err
+		}
+		buffer := ringBuffer{Values: legacy.Buffer, Pos: legacy.Pos, Count: legacy.Count}
+		if err := buffer.check(ind.period); err != nil {
+			return err
+		}
+		ind.state = smaState{Sum: legacy.Sum, Buffer: buffer}
+		return nil
+	}
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+type emaIndicator struct {
+	maIndicator
+	state emaState
+}
+
+func (ind *emaIndicator) Update(data Data) (Point, bool) {
+	return ind.state.build(columnValue(data, ind.column), ind.period)
+}
+
+func (ind *emaIndicator) WarmupPeriod() int {
+	return 1
+}
+
+func (ind *emaIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *emaIndicator) UnmarshalState(state []byte) error {
+	if isJSONIndicatorState(state) {
+		legacy, err := parseLegacyMAState(state)
+		if err != nil {
+			return err
+		}
+		ind.state = emaState{EMA: legacy.EMA, Count: legacy.Count}
+		return nil
+	}
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+type wmaIndicator struct {
+	maIndicator
+	state wmaState
+}
+
+func (ind *wmaIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.state = newWMAState(ind.period)
+	return nil
+}
+
+func (ind *wmaIndicator) Update(data Data) (Point, bool) {
+	return ind.state.build(columnValue(data, ind.column))
+}
+
+func (ind *wmaIndicator) WarmupPeriod() int {
+	return ind.period
+}
+
+func (ind *wmaIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *wmaIndicator) UnmarshalState(state []byte) error {
+	if isJSONIndicatorState(state) {
+		legacy, err := parseLegacyMAState(state)
+		if err != nil {
+			return err
+		}
+		ind.state, err = legacy.wma(ind.period)
+		return err
+	}
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+type hmaIndicator struct {
+	maIndicator
+	state hmaState
+}
+
+func (ind *hmaIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.state = newHMAState(ind.period)
+	return nil
+}
+
+func (ind *hmaIndicator) Update(data Data) (Point, bool) {
+	return ind.state.build(columnValue(data, ind.column))
+}
+
+// the WMA over the square root of the period starts once the full period WMA is ready
+func (ind *hmaIndicator) WarmupPeriod() int {
+	return ind.period + hmaSqrtPeriod(ind.period) - 1
+}
+
+func (ind *hmaIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *hmaIndicator) UnmarshalState(state []byte) error {
+	if isJSONIndicatorState(state) {
+		legacy, err := parseLegacyMAState(state)
+		if err != nil {
+			return err
+		}
+		if len(legacy.AltState) == 3 {
+			ind.state, err = legacy.hma(ind.state)
+		}
+		return err
+	}
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+/* DOUBLE EXPONENTIAL MOVING AVERAGE : DEMA */
+type demaState struct {
+	EMA1 emaState
+	// EMA of EMA1
+	EMA2 emaState
+}
+
+func (state *demaState) build(value float64, period int) (Point, bool) {
+	ema1, _ := state.EMA1.build(value, period)
+	ema2, _ := state.EMA2.build(ema1.Value, period)
+	return Point{2*ema1.Value - ema2.Value}, true
+}
+
+func (state *demaState) encode(e *stateEncoder) {
+	state.EMA1.encode(e)
+	state.EMA2.encode(e)
+}
+
+func (state *demaState) decode(d *stateDecoder) {
+	state.EMA1.decode(d)
+	state.EMA2.decode(d)
+}
+
+/* TRIPLE EXPONENTIAL MOVING AVERAGE : TEMA */
+type temaState struct {
+	EMA1 emaState
+	EMA2 emaState
+	EMA3 emaState
+}
+
+func (state *temaState) build(value float64, period int) (Point, bool) {
+	ema1, _ := state.EMA1.build(value, period)
+	ema2, _ := state.EMA2.build(ema1.Value, period)
+	ema3, _ := state.EMA3.build(ema2.Value, period)
+	return Point{3*ema1.Value - 3*ema2.Value + ema3.Value}, true
+}
+
+func (state *temaState) encode(e *stateEncoder) {
+	state.EMA1.encode(e)
+	state.EMA2.encode(e)
+	state.EMA3.encode(e)
+}
+
+func (state *temaState) decode(d *stateDecoder) {
+	state.EMA1.decode(d)
+	state.EMA2.decode(d)
+	state.EMA3.decode(d)
+}
+
+/* ZERO LAG EXPONENTIAL MOVING AVERAGE : ZLEMA */
+type zlemaState struct {
+	// the last lag + 1 values
+	Buffer ringBuffer
+	EMA    emaState
+}
+
+func zlemaLag(period int) int {
+	return (period - 1) / 2
+}
+
+func newZLEMAState(period int) zlemaState {
+	return zlemaState{Buffer: newRingBuffer(zlemaLag(period) + 1)}
+}
+
+// build computes the EMA of the value corrected by its momentum over the lag.
+func (state *zlemaState) build(value float64, period int) (Point, bool) {
+	state.Buffer.Push(value)
+	if !state.Buffer.Full() {
+		return Point{}, false
+	}
+	lagged := state.Buffer.At(0)
+	return state.EMA.build(2*value-lagged, period)
+}
+
+func (state *zlemaState) encode(e *stateEncoder) {
+	e.Ring(state.Buffer)
+	state.EMA.encode(e)
+}
+
+func (state *zlemaState) decode(d *stateDecoder) {
+	d.Ring(&state.Buffer)
+	state.EMA.decode(d)
+}
+
+/* KAUFMAN ADAPTIVE MOVING AVERAGE : KAMA */
+type kamaState struct {
+	// the last period + 1 values
+	Buffer ringBuffer
+	// sum of the absolute changes of the last period values
+	Volatility rollingSum
+	KAMA       float64
+	Ready      bool
+}
+
+func newKAMAState(period int) kamaState {
+	return kamaState{Buffer: newRingBuffer(period + 1), Volatility: newRollingSum(period)}
+}
+
+// build moves the average toward the value, faster when the price trends (efficiency ratio close to 1)
+// and slower when it is noisy.
+func (state *kamaState) build(value float64, fastPeriod, slowPeriod int) (Point, bool) {
+	if state.Buffer.Count > 0 {
+		prev := state.Buffer.At(state.Buffer.Len() - 1)
+		state.Volatility.Push(math.Abs(value - prev))
+	}
+	state.Buffer.Push(value)
+	if !state.Buffer.Full() {
+		return Point{}, false
+	}
+	if !state.Ready {
+		state.KAMA = value
+		state.Ready = true
+		return Point{state.KAMA}, true
+	}
+
+	efficiency := 0.0
+	if state.Volatility.Sum > 0 {
+		efficiency = math.Abs(value-state.Buffer.At(0)) / state.Volatility.Sum
+	}
+	fast := 2.0 / float64(fastPeriod+1)
+	slow := 2.0 / float64(slowPeriod+1)
+	smoothing := math.Pow(efficiency*(fast-slow)+slow, 2)
+	state.KAMA += smoothing * (value - state.KAMA)
+	return Point{state.KAMA}, true
+}
+
+func (state *kamaState) encode(e *stateEncoder) {
+	e.Ring(state.Buffer)
+	state.Volatility.encode(e)
+	e.Float(state.KAMA)
+	e.Bool(state.Ready)
+}
+
+func (state *kamaState) decode(d *stateDecoder) {
+	d.Ring(&state.Buffer)
+	state.Volatility.decode(d)
+	state.KAMA = d.Float()
+	state.Ready = d.Bool()
+}
+
+type demaIndicator struct {
+	maIndicator
+	state demaState
+}
+
+func (ind *demaIndicator) Update(data Data) (Point, bool) {
+	return ind.state.build(columnValue(data, ind.column), ind.period)
+}
+
+// like the EMA, the DEMA starts from the first value
+func (ind *demaIndicator) WarmupPeriod() int {
+	return 1
+}
+
+func (ind *demaIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *demaIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+type temaIndicator struct {
+	maIndicator
+	state temaState
+}
+
+func (ind *temaIndicator) Update(data Data) (Point, bool) {
+	return ind.state.build(columnValue(data, ind.column), ind.period)
+}
+
+// like the EMA, the TEMA starts from the first value
+func (ind *temaIndicator) WarmupPeriod() int {
+	return 1
+}
+
+func (ind *temaIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *temaIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+type zlemaIndicator struct {
+	maIndicator
+	state zlemaState
+}
+
+func (ind *zlemaIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.state = newZLEMAState(ind.period)
+	return nil
+}
+
+func (ind *zlemaIndicator) Update(data Data) (Point, bool) {
+	return ind.state.build(columnValue(data, ind.column), ind.period)
+}
+
+func (ind *zlemaIndicator) WarmupPeriod() int {
+	return zlemaLag(ind.period) + 1
+}
+
+func (ind *zlemaIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *zlemaIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+type kamaIndicator struct {
+	maIndicator
+	fastPeriod int
+	slowPeriod int
+	state      kamaState
+}
+
+func (ind *kamaIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.fastPeriod = int(args.Int("fast"))
+	ind.slowPeriod = int(args.Int("slow"))
+	if ind.fastPeriod >= ind.slowPeriod {
+		return errors.New("fast period must be lower than slow period")
+	}
+	ind.state = newKAMAState(ind.period)
+	return nil
+}
+
+func (ind *kamaIndicator) Update(data Data) (Point, bool) {
+	return ind.state.build(columnValue(data, ind.column), ind.fastPeriod, ind.slowPeriod)
+}
+
+// the efficiency ratio compares the value to the one period values before
+func (ind *kamaIndicator) WarmupPeriod() int {
+	return ind.period + 1
+}
+
+func (ind *kamaIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *kamaIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
 }
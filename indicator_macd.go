@@ -0,0 +1,81 @@
+package pcommon
+
+import "errors"
+
+/* MOVING AVERAGE CONVERGENCE DIVERGENCE : MACD */
+
+var macdColumns = []ColumnName{"macd", "signal", "histogram"}
+
+type macdState struct {
+	Fast   emaState
+	Slow   emaState
+	Signal emaState
+}
+
+// build returns the MACD line, the signal line and the histogram.
+// The signal line starts once the slow EMA covers its whole period.
+func (state *macdState) build(value float64, fastPeriod, slowPeriod, signalPeriod int) ([]float64, bool) {
+	fast, _ := state.Fast.build(value, fastPeriod)
+	slow, _ := state.Slow.build(value, slowPeriod)
+	if state.Slow.Count < slowPeriod {
+		return nil, false
+	}
+
+	macd := fast.Value - slow.Value
+	signal, _ := state.Signal.build(macd, signalPeriod)
+	if state.Signal.Count < signalPeriod {
+		return nil, false
+	}
+	return []float64{macd, signal.Value, macd - signal.Value}, true
+}
+
+func (state *macdState) encode(e *stateEncoder) {
+	state.Fast.encode(e)
+	state.Slow.encode(e)
+	state.Signal.encode(e)
+}
+
+func (state *macdState) decode(d *stateDecoder) {
+	state.Fast.decode(d)
+	state.Slow.decode(d)
+	state.Signal.decode(d)
+}
+
+type macdIndicator struct {
+	column       ColumnName
+	fastPeriod   int
+	slowPeriod   int
+	signalPeriod int
+	state        macdState
+}
+
+func (ind *macdIndicator) Init(args IndicatorArguments) error {
+	ind.column = args.Column("column")
+	ind.fastPeriod = int(args.Int("fast"))
+	ind.slowPeriod = int(args.Int("slow"))
+	ind.signalPeriod = int(args.Int("signal"))
+	if ind.fastPeriod >= ind.slowPeriod {
+		return errors.New("fast period must be lower than slow period")
+	}
+	return nil
+}
+
+func (ind *macdIndicator) UpdateVector(data Data) ([]float64, bool) {
+	return ind.state.build(columnValue(data, ind.column), ind.fastPeriod, ind.slowPeriod, ind.signalPeriod)
+}
+
+func (ind *macdIndicator) WarmupPeriod() int {
+	return ind.slowPeriod + ind.signalPeriod - 1
+}
+
+func (ind *macdIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *macdIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
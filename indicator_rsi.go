@@ -1,5 +1,7 @@
 package pcommon
 
+import "encoding/json"
+
 /* RELATIVE STRENGTH INDEX : RSI */
 type rsiState struct {
 	AvgGain   float64 `json:"avg_gain"`
@@ -9,13 +11,13 @@ type rsiState struct {
 	Pos       int64   `json:"pos"`
 }
 
-func (state *rsiState) buildRSI(value float64, RSI_PERIOD int64) *Point {
+func (state *rsiState) buildRSI(value float64, RSI_PERIOD int64) (Point, bool) {
 	defer func() {
 		state.LastClose = value
 	}()
 
 	if state.LastClose <= 0 {
-		return &Point{Value: -1}
+		return Point{}, false
 	}
 
 	change := value - state.LastClose
@@ -36,7 +38,7 @@ func (state *rsiState) buildRSI(value float64, RSI_PERIOD int64) *Point {
 		state.AvgGain += gain
 		state.AvgLoss += loss
 		if state.Pos < RSI_PERIOD {
-			return &Point{-1} // Not enough data to compute RSI yet
+			return Point{}, false // Not enough data to compute RSI yet
 		}
 	}
 
@@ -56,5 +58,54 @@ func (state *rsiState) buildRSI(value float64, RSI_PERIOD int64) *Point {
 		state.LastRSI = float64(100 - (100 / (1 + rs)))
 	}
 
-	return &Point{state.LastRSI}
+	return Point{state.LastRSI}, true
+}
+
+// rsiIndicator computes RSI on the close of a unit, and RSI2 on any column.
+type rsiIndicator struct {
+	column ColumnName
+	period int64
+	state  rsiState
+}
+
+func (ind *rsiIndicator) Init(args IndicatorArguments) error {
+	ind.column = ColumnType.CLOSE
+	if args.Has("column") {
+		ind.column = args.Column("column")
+	}
+	ind.period = args.Int("period")
+	return nil
+}
+
+func (ind *rsiIndicator) Update(data Data) (Point, bool) {
+	return ind.state.buildRSI(columnValue(data, ind.column), ind.period)
+}
+
+func (ind *rsiIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	e.Float(ind.state.AvgGain)
+	e.Float(ind.state.AvgLoss)
+	e.Float(ind.state.LastRSI)
+	e.Float(ind.state.LastClose)
+	e.Int(ind.state.Pos)
+	return e.Bytes(), nil
+}
+
+// states saved before the binary format are JSON encoded rsiState
+func (ind *rsiIndicator) UnmarshalState(state []byte) error {
+	if isJSONIndicatorState(state) {
+		return json.Unmarshal(state, &ind.state)
+	}
+	d := newStateDecoder(state)
+	ind.state.AvgGain = d.Float()
+	ind.state.AvgLoss = d.Float()
+	ind.state.LastRSI = d.Float()
+	ind.state.LastClose = d.Float()
+	ind.state.Pos = d.Int()
+	return d.Err()
+}
+
+// the first value only sets the previous close
+func (ind *rsiIndicator) WarmupPeriod() int {
+	return int(ind.period) + 1
 }
@@ -0,0 +1,430 @@
+package pcommon
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+/*
+	Binary format of the indicator states:
+
+	[magic byte][version byte][fields...]
+
+	- floats are 8 bytes big endian
+	- integers are varints
+	- ring buffers are their length (uvarint), their values, their position and their count
+	- monotonic deques are their values, their indexes (length and varints) and their count
+
+	Ring buffers and deques are decoded into the ones sized by the Init of the indicator,
+	a state that does not match the arguments of the indicator is rejected.
+
+	States saved before this format are JSON objects: they start with '{' and are still readable.
+*/
+
+const indicatorStateMagic byte = 0xB1
+const indicatorStateVersion byte = 1
+
+func isJSONIndicatorState(state []byte) bool {
+	return len(state) > 0 && state[0] == '{'
+}
+
+type stateEncoder struct {
+	buf []byte
+}
+
+func newStateEncoder() *stateEncoder {
+	return &stateEncoder{buf: []byte{indicatorStateMagic, indicatorStateVersion}}
+}
+
+func (e *stateEncoder) Float(v float64) {
+	e.buf = binary.BigEndian.AppendUint64(e.buf, math.Float64bits(v))
+}
+
+func (e *stateEncoder) Int(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+func (e *stateEncoder) Bool(v bool) {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+func (e *stateEncoder) Floats(values []float64) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(values)))
+	for _, v := range values {
+		e.Float(v)
+	}
+}
+
+func (e *stateEncoder) Ints(values []int64) {
+	e.buf = binary.AppendUvarint(e.buf, uint64(len(values)))
+	for _, v := range values {
+		e.Int(v)
+	}
+}
+
+func (e *stateEncoder) Ring(r ringBuffer) {
+	e.Floats(r.Values)
+	e.Int(int64(r.Pos))
+	e.Int(int64(r.Count))
+}
+
+func (e *stateEncoder) Deque(q monotonicDeque) {
+	e.Floats(q.Values)
+	e.Ints(q.Indexes)
+	e.Int(q.Count)
+}
+
+func (e *stateEncoder) Bytes() []byte {
+	return e.buf
+}
+
+// stateDecoder reads the fields in the order they were encoded, the first error is kept and returned by Err.
+type stateDecoder struct {
+	buf []byte
+	err error
+}
+
+func newStateDecoder(state []byte) *stateDecoder {
+	d := &stateDecoder{}
+	if len(state) < 2 || state[0] != indicatorStateMagic {
+		d.err = errors.New("invalid indicator state")
+		return d
+	}
+	if state[1] != indicatorStateVersion {
+		d.err = fmt.Errorf("unsupported indicator state version %d", state[1])
+		return d
+	}
+	d.buf = state[2:]
+	return d
+}
+
+func (d *stateDecoder) Float() float64 {
+	if d.err != nil {
+		return 0
+	}
+	if len(d.buf) < 8 {
+		d.err = errors.New("indicator state is truncated")
+		return 0
+	}
+	v := math.Float64frombits(binary.BigEndian.Uint64(d.buf))
+	d.buf = d.buf[8:]
+	return v
+}
+
+func (d *stateDecoder) Int() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.err = errors.New("indicator state is truncated")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *stateDecoder) Bool() bool {
+	if d.err != nil {
+		return false
+	}
+	if len(d.buf) < 1 {
+		d.err = errors.New("indicator state is truncated")
+		return false
+	}
+	v := d.buf[0] == 1
+	d.buf = d.buf[1:]
+	return v
+}
+
+func (d *stateDecoder) Floats() []float64 {
+	if d.err != nil {
+		return nil
+	}
+	length, n := binary.Uvarint(d.buf)
+	// compared by division, length*8 may overflow
+	if n <= 0 || length > uint64(len(d.buf)-n)/8 {
+		d.err = errors.New("indicator state is truncated")
+		return nil
+	}
+	d.buf = d.buf[n:]
+	values := make([]float64, length)
+	for i := range values {
+		values[i] = d.Float()
+	}
+	return values
+}
+
+func (d *stateDecoder) Ints() []int64 {
+	if d.err != nil {
+		return nil
+	}
+	length, n := binary.Uvarint(d.buf)
+	// a varint takes at least one byte
+	if n <= 0 || uint64(len(d.buf)-n) < length {
+		d.err = errors.New("indicator state is truncated")
+		return nil
+	}
+	d.buf = d.buf[n:]
+	values := make([]int64, length)
+	for i := range values {
+		values[i] = d.Int()
+	}
+	return values
+}
+
+// Ring reads a ring buffer encoded with Ring into r, which must have the size set by the indicator.
+func (d *stateDecoder) Ring(r *ringBuffer) {
+	values := d.Floats()
+	pos := d.Int()
+	count := d.Int()
+	if d.err != nil {
+		return
+	}
+	decoded := ringBuffer{Values: values, Pos: int(pos), Count: int(count)}
+	if err := decoded.check(len(r.Values)); err != nil {
+		d.err = err
+		return
+	}
+	// the position of the next value is always the number of values pushed modulo the size
+	if pos != count%int64(len(values)) {
+		d.err = errors.New("invalid ring buffer in indicator state")
+		return
+	}
+	*r = decoded
+}
+
+// Deque reads a deque encoded with Deque into q, its window and direction being set by the indicator.
+func (d *stateDecoder) Deque(q *monotonicDeque) {
+	values := d.Floats()
+	indexes := d.Ints()
+	count := d.Int()
+	if d.err != nil {
+		return
+	}
+	if q.Window <= 0 || len(values) != len(indexes) || len(values) > q.Window || count < 0 {
+		d.err = errors.New("invalid deque in indicator state")
+		return
+	}
+	// the candidates are the last values of the window, from the oldest one, the last value pushed being the last one
+	for i, index := range indexes {
+		if index >= count || index < count-int64(q.Window) || (i > 0 && index <= indexes[i-1]) {
+			d.err = errors.New("invalid deque in indicator state")
+			return
+		}
+	}
+	if count > 0 && (len(indexes) == 0 || indexes[len(indexes)-1] != count-1) {
+		d.err = errors.New("invalid deque in indicator state")
+		return
+	}
+	// each candidate is strictly greater (or lower) than the next ones, the ones it dominates being dropped
+	for i := 1; i < len(values); i++ {
+		if !(values[i-1] > values[i] && q.Max || values[i-1] < values[i] && !q.Max) {
+			d.err = errors.New("unsorted deque in indicator state")
+			return
+		}
+	}
+	q.Values = values
+	q.Indexes = indexes
+	q.Count = count
+}
+
+// checkDerived records an error if a value derived from the buffers of a state (ex: their sum) differs from the one recomputed from them.
+// The sliding updates accumulate rounding errors: the values are compared relatively to scale, the magnitude of the terms summed.
+func (d *stateDecoder) checkDerived(name string, decoded, recomputed, scale float64) {
+	if !(math.Abs(decoded-recomputed) <= 1e-6*scale) {
+		d.fail(fmt.Errorf("inconsistent %s in indicator state", name))
+	}
+}
+
+// fail records err if no error occurred before, for the checks of the decoded values done by the indicators.
+func (d *stateDecoder) fail(err error) {
+	if d.err == nil {
+		d.err = err
+	}
+}
+
+func (d *stateDecoder) Err() error {
+	if d.err == nil && len(d.buf) > 0 {
+		return errors.New("unexpected bytes at the end of the indicator state")
+	}
+	return d.err
+}
+
+// ringBuffer keeps the last len(Values) values pushed.
+type ringBuffer struct {
+	Values []float64
+	// index of the oldest value once the buffer is full
+	Pos int
+	// number of values pushed
+	Count int
+}
+
+func newRingBuffer(size int) ringBuffer {
+	return ringBuffer{Values: make([]float64, size)}
+}
+
+// Push adds a value and returns the one it replaces (0 while the buffer is not full).
+func (r *ringBuffer) Push(v float64) float64 {
+	old := r.Values[r.Pos]
+	r.Values[r.Pos] = v
+	r.Pos = (r.Pos + 1) % len(r.Values)
+	r.Count++
+	return old
+}
+
+// check returns an error if a ring buffer read from a state does not hold size values or has an invalid position.
+func (r ringBuffer) check(size int) error {
+	if size <= 0 || len(r.Values) != size {
+		return fmt.Errorf("ring buffer of %d values in indicator state, expected %d", len(r.Values), size)
+	}
+	if r.Pos < 0 || r.Pos >= size || r.Count < 0 {
+		return errors.New("invalid ring buffer in indicator state")
+	}
+	return nil
+}
+
+func (r *ringBuffer) Full() bool {
+	return r.Count >= len(r.Values)
+}
+
+// At returns the i-th value of the buffer, from the oldest one.
+func (r *ringBuffer) At(i int) float64 {
+	if !r.Full() {
+		return r.Values[i]
+	}
+	return r.Values[(r.Pos+i)%len(r.Values)]
+}
+
+// sum returns the sum of the values held and the sum of their absolute values.
+func (r *ringBuffer) sum() (sum float64, abs float64) {
+	for i := 0; i < r.Len(); i++ {
+		sum += r.At(i)
+		abs += math.Abs(r.At(i))
+	}
+	return sum, abs
+}
+
+// Len returns the number of values held.
+func (r *ringBuffer) Len() int {
+	if r.Full() {
+		return len(r.Values)
+	}
+	return r.Count
+}
+
+// rollingSum keeps the sum of the last len(Buffer.Values) values pushed.
+type rollingSum struct {
+	Buffer ringBuffer
+	Sum    float64
+}
+
+func newRollingSum(size int) rollingSum {
+	return rollingSum{Buffer: newRingBuffer(size)}
+}
+
+// Push adds a value and returns the sum of the window.
+func (r *rollingSum) Push(v float64) float64 {
+	r.Sum += v - r.Buffer.Push(v)
+	// the sliding sum accumulates rounding errors, it is recomputed once per window (amortized O(1))
+	if r.Buffer.Count%len(r.Buffer.Values) == 0 {
+		r.Sum = 0
+		for _, value := range r.Buffer.Values {
+			r.Sum += value
+		}
+	}
+	return r.Sum
+}
+
+func (r *rollingSum) Full() bool {
+	return r.Buffer.Full()
+}
+
+func (r *rollingSum) encode(e *stateEncoder) {
+	e.Ring(r.Buffer)
+	e.Float(r.Sum)
+}
+
+func (r *rollingSum) decode(d *stateDecoder) {
+	d.Ring(&r.Buffer)
+	r.Sum = d.Float()
+	if d.err == nil {
+		sum, scale := r.Buffer.sum()
+		d.checkDerived("sum", r.Sum, sum, scale)
+	}
+}
+
+// monotonicDeque keeps the maximum (or the minimum) of the last Window values pushed, in amortized O(1).
+type monotonicDeque struct {
+	Window int
+	Max    bool
+	// candidates to be the extremum, from the oldest one
+	Values []float64
+	// position of each candidate in the values pushed
+	Indexes []int64
+	// number of values pushed
+	Count int64
+}
+
+func newMonotonicDeque(window int, max bool) monotonicDeque {
+	return monotonicDeque{Window: window, Max: max}
+}
+
+// Push adds a value and returns the extremum of the window.
+func (q *monotonicDeque) Push(v float64) float64 {
+	// values dominated by the new one can never be the extremum again
+	for len(q.Values) > 0 && q.dominated(q.Values[len(q.Values)-1], v) {
+		q.Values = q.Values[:len(q.Values)-1]
+		q.Indexes = q.Indexes[:len(q.Indexes)-1]
+	}
+	q.Values = append(q.Values, v)
+	q.Indexes = append(q.Indexes, q.Count)
+	q.Count++
+
+	// drop the candidates out of the window
+	for q.Indexes[0] <= q.Count-1-int64(q.Window) {
+		q.Values = q.Values[1:]
+		q.Indexes = q.Indexes[1:]
+	}
+	return q.Values[0]
+}
+
+func (q *monotonicDeque) dominated(old, v float64) bool {
+	if q.Max {
+		return old <= v
+	}
+	return old >= v
+}
+
+// Extremum returns the maximum (or the minimum) of the window.
+func (q *monotonicDeque) Extremum() float64 {
+	if len(q.Values) == 0 {
+		return 0
+	}
+	return q.Values[0]
+}
+
+func (q *monotonicDeque) Full() bool {
+	return q.Count >= int64(q.Window)
+}
+
+// statelessIndicator is embedded by the indicators computing a value from the dependency values at the same time only.
+type statelessIndicator struct{}
+
+func (statelessIndicator) WarmupPeriod() int {
+	return 1
+}
+
+func (statelessIndicator) MarshalState() ([]byte, error) {
+	return nil, nil
+}
+
+func (statelessIndicator) UnmarshalState(state []byte) error {
+	return nil
+}
@@ -0,0 +1,372 @@
+package pcommon
+
+import (
+	"errors"
+	"math"
+)
+
+/*
+	Rolling statistics over any column of any dependency.
+	The rolling mean and standard deviation are the SMA and STDDEV assets.
+*/
+
+/* Z-SCORE */
+type zscoreIndicator struct {
+	maIndicator
+	state stddevState
+}
+
+func (ind *zscoreIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.state = newStddevState(ind.period)
+	return nil
+}
+
+// Update returns the number of standard deviations between the value and the mean of the window.
+func (ind *zscoreIndicator) Update(data Data) (Point, bool) {
+	value := columnValue(data, ind.column)
+	stddev, ready := ind.state.build(value)
+	if !ready {
+		return Point{}, false
+	}
+	if stddev.Value == 0 {
+		return Point{0}, true
+	}
+	return Point{(value - ind.state.Mean) / stddev.Value}, true
+}
+
+func (ind *zscoreIndicator) WarmupPeriod() int {
+	return ind.period
+}
+
+func (ind *zscoreIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *zscoreIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+/* PERCENTILE RANK */
+
+// rankTree is a treap of the values of a window counting the values lower than or equal to a value in O(log n).
+// Equal values share a node. Its nodes are taken from a pool, so that a full window does not allocate.
+type rankTree struct {
+	nodes []rankNode
+	// indexes of the nodes of the pool not in the tree
+	free []int
+	// index of the root node, -1 if the tree is empty
+	root int
+	// state of the xorshift generator of the priorities
+	seed uint64
+}
+
+type rankNode struct {
+	value    float64
+	priority uint64
+	// -1 if there is no child
+	left, right int
+	// number of values equal to value
+	count int
+	// number of values in the subtree
+	size int
+}
+
+func newRankTree(capacity int) rankTree {
+	return rankTree{nodes: make([]rankNode, 0, capacity), root: -1, seed: 0x9e3779b97f4a7c15}
+}
+
+// Insert adds a value, which must not be NaN.
+func (t *rankTree) Insert(v float64) {
+	t.root = t.insert(t.root, v)
+}
+
+// Remove removes a value added with Insert.
+func (t *rankTree) Remove(v float64) {
+	t.root = t.remove(t.root, v)
+}
+
+// Rank returns the number of values lower than v and the number of values equal to v.
+func (t *rankTree) Rank(v float64) (lower int, equal int) {
+	for i := t.root; i >= 0; {
+		n := &t.nodes[i]
+		switch {
+		case v < n.value:
+			i = n.left
+		case v > n.value:
+			lower += t.size(n.left) + n.count
+			i = n.right
+		default:
+			return lower + t.size(n.left), n.count
+		}
+	}
+	return lower, 0
+}
+
+// Len returns the number of values in the tree.
+func (t *rankTree) Len() int {
+	return t.size(t.root)
+}
+
+func (t *rankTree) size(i int) int {
+	if i < 0 {
+		return 0
+	}
+	return t.nodes[i].size
+}
+
+func (t *rankTree) newNode(v float64) int {
+	t.seed ^= t.seed << 13
+	t.seed ^= t.seed >> 7
+	t.seed ^= t.seed << 17
+	node := rankNode{value: v, priority: t.seed, left: -1, right: -1, count: 1, size: 1}
+	if n := len(t.free); n > 0 {
+		i := t.free[n-1]
+		t.free = t.free[:n-1]
+		t.nodes[i] = node
+		return i
+	}
+	t.nodes = append(t.nodes, node)
+	return len(t.nodes) - 1
+}
+
+func (t *rankTree) resize(i int) {
+	n := &t.nodes[i]
+	n.size = n.count + t.size(n.left) + t.size(n.right)
+}
+
+func (t *rankTree) rotateRight(i int) int {
+	l := t.nodes[i].left
+	t.nodes[i].left = t.nodes[l].right
+	t.nodes[l].right = i
+	t.resize(i)
+	t.resize(l)
+	return l
+}
+
+func (t *rankTree) rotateLeft(i int) int {
+	r := t.nodes[i].right
+	t.nodes[i].right = t.nodes[r].left
+	t.nodes[r].left = i
+	t.resize(i)
+	t.resize(r)
+	return r
+}
+
+// insert adds v to the subtree of i and returns the index of its new root.
+func (t *rankTree) insert(i int, v float64) int {
+	if i < 0 {
+		return t.newNode(v)
+	}
+	switch {
+	case v < t.nodes[i].value:
+		l := t.insert(t.nodes[i].left, v)
+		t.nodes[i].left = l
+		if t.nodes[l].priority > t.nodes[i].priority {
+			return t.rotateRight(i)
+		}
+	case v > t.nodes[i].value:
+		r := t.insert(t.nodes[i].right, v)
+		t.nodes[i].right = r
+		if t.nodes[r].priority > t.nodes[i].priority {
+			return t.rotateLeft(i)
+		}
+	default:
+		t.nodes[i].count++
+	}
+	t.resize(i)
+	return i
+}
+
+// remove removes v from the subtree of i and returns the index of its new root.
+func (t *rankTree) remove(i int, v float64) int {
+	if i < 0 {
+		return i
+	}
+	n := &t.nodes[i]
+	switch {
+	case v < n.value:
+		n.left = t.remove(n.left, v)
+	case v > n.value:
+		n.right = t.remove(n.right, v)
+	case n.count > 1:
+		n.count--
+	default:
+		// the node is rotated down until it is a leaf
+		l, r := n.left, n.right
+		if l < 0 && r < 0 {
+			t.free = append(t.free, i)
+			return -1
+		}
+		var root int
+		if r < 0 || (l >= 0 && t.nodes[l].priority > t.nodes[r].priority) {
+			root = t.rotateRight(i)
+			t.nodes[root].right = t.remove(t.nodes[root].right, v)
+		} else {
+			root = t.rotateLeft(i)
+			t.nodes[root].left = t.remove(t.nodes[root].left, v)
+		}
+		t.resize(root)
+		return root
+	}
+	t.resize(i)
+	return i
+}
+
+// percentileRankState keeps the values of the window in a rank tree to rank a value in O(log n).
+// NaN values cannot be ranked, they are skipped.
+type percentileRankState struct {
+	Buffer ringBuffer
+	// values of the buffer, rebuilt from the buffer when the state is loaded
+	tree rankTree
+}
+
+func newPercentileRankState(period int) percentileRankState {
+	return percentileRankState{Buffer: newRingBuffer(period), tree: newRankTree(period)}
+}
+
+// build returns the percentage of the other values of the window lower than the value, equal values counting for half.
+func (state *percentileRankState) build(value float64) (Point, bool) {
+	if math.IsNaN(value) {
+		return Point{}, false
+	}
+	full := state.Buffer.Full()
+	old := state.Buffer.Push(value)
+	if full {
+		state.tree.Remove(old)
+	}
+	state.tree.Insert(value)
+
+	if !state.Buffer.Full() {
+		return Point{}, false
+	}
+
+	lower, equal := state.tree.Rank(value)
+	others := float64(state.tree.Len() - 1)
+	return Point{100 * (float64(lower) + float64(equal-1)/2) / others}, true
+}
+
+func (state *percentileRankState) encode(e *stateEncoder) {
+	e.Ring(state.Buffer)
+}
+
+func (state *percentileRankState) decode(d *stateDecoder) {
+	d.Ring(&state.Buffer)
+	state.tree = newRankTree(len(state.Buffer.Values))
+	for i := 0; i < state.Buffer.Len(); i++ {
+		v := state.Buffer.At(i)
+		if math.IsNaN(v) {
+			d.fail(errors.New("invalid value in indicator state"))
+			return
+		}
+		state.tree.Insert(v)
+	}
+}
+
+type percentileRankIndicator struct {
+	maIndicator
+	state percentileRankState
+}
+
+func (ind *percentileRankIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.state = newPercentileRankState(ind.period)
+	return nil
+}
+
+func (ind *percentileRankIndicator) Update(data Data) (Point, bool) {
+	return ind.state.build(columnValue(data, ind.column))
+}
+
+func (ind *percentileRankIndicator) WarmupPeriod() int {
+	return ind.period
+}
+
+func (ind *percentileRankIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *percentileRankIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+/* ROLLING MIN AND MAX */
+type rollingExtremumIndicator struct {
+	maIndicator
+	max   bool
+	state monotonicDeque
+}
+
+func (ind *rollingExtremumIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.state = newMonotonicDeque(ind.period, ind.max)
+	return nil
+}
+
+func (ind *rollingExtremumIndicator) Update(data Data) (Point, bool) {
+	extremum := ind.state.Push(columnValue(data, ind.column))
+	if !ind.state.Full() {
+		return Point{}, false
+	}
+	return Point{extremum}, true
+}
+
+func (ind *rollingExtremumIndicator) WarmupPeriod() int {
+	return ind.period
+}
+
+func (ind *rollingExtremumIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	e.Deque(ind.state)
+	return e.Bytes(), nil
+}
+
+func (ind *rollingExtremumIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	d.Deque(&ind.state)
+	return d.Err()
+}
+
+/* ROLLING SUM */
+type rollingSumIndicator struct {
+	maIndicator
+	state rollingSum
+}
+
+func (ind *rollingSumIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.state = newRollingSum(ind.period)
+	return nil
+}
+
+func (ind *rollingSumIndicator) Update(data Data) (Point, bool) {
+	sum := ind.state.Push(columnValue(data, ind.column))
+	if !ind.state.Full() {
+		return Point{}, false
+	}
+	return Point{sum}, true
+}
+
+func (ind *rollingSumIndicator) WarmupPeriod() int {
+	return ind.period
+}
+
+func (ind *rollingSumIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *rollingSumIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
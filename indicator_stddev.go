@@ -0,0 +1,181 @@
+package pcommon
+
+import "math"
+
+/* ROLLING STANDARD DEVIATION : STDDEV */
+
+// stddevState keeps the mean and the sum of squared deviations (M2) of the window up to date
+// with Welford's algorithm, adapted to replace the oldest value once the window is full.
+type stddevState struct {
+	Mean   float64
+	M2     float64
+	Buffer ringBuffer
+}
+
+func newStddevState(period int) stddevState {
+	return stddevState{Buffer: newRingBuffer(period)}
+}
+
+// build returns the population standard deviation of the window.
+func (state *stddevState) build(value float64) (Point, bool) {
+	full := state.Buffer.Full()
+	old := state.Buffer.Push(value)
+	if full {
+		n := float64(len(state.Buffer.Values))
+		prevMean := state.Mean
+		state.Mean += (value - old) / n
+		state.M2 += (value - old) * (value - state.Mean + old - prevMean)
+	} else {
+		n := float64(state.Buffer.Count)
+		delta := value - state.Mean
+		state.Mean += delta / n
+		state.M2 += delta * (value - state.Mean)
+	}
+	// the sliding updates accumulate rounding errors, the window is recomputed once per period (amortized O(1))
+	if state.Buffer.Count%len(state.Buffer.Values) == 0 {
+		state.resync()
+	}
+	// rounding errors can make M2 slightly negative when the window is flat
+	if state.M2 < 0 {
+		state.M2 = 0
+	}
+
+	if !state.Buffer.Full() {
+		return Point{}, false
+	}
+	return Point{math.Sqrt(state.M2 / float64(len(state.Buffer.Values)))}, true
+}
+
+// resync recomputes the mean and M2 of the full window with two passes.
+func (state *stddevState) resync() {
+	n := float64(len(state.Buffer.Values))
+	sum := 0.0
+	for _, v := range state.Buffer.Values {
+		sum += v
+	}
+	state.Mean = sum / n
+	state.M2 = 0
+	for _, v := range state.Buffer.Values {
+		state.M2 += (v - state.Mean) * (v - state.Mean)
+	}
+}
+
+func (state *stddevState) encode(e *stateEncoder) {
+	e.Float(state.Mean)
+	e.Float(state.M2)
+	e.Ring(state.Buffer)
+}
+
+func (state *stddevState) decode(d *stateDecoder) {
+	state.Mean = d.Float()
+	state.M2 = d.Float()
+	d.Ring(&state.Buffer)
+	if d.err != nil {
+		return
+	}
+
+	// the moments must be the ones of the window
+	n := state.Buffer.Len()
+	sum, abs := state.Buffer.sum()
+	mean, m2, squares := 0.0, 0.0, 0.0
+	if n > 0 {
+		mean = sum / float64(n)
+	}
+	for i := 0; i < n; i++ {
+		v := state.Buffer.At(i)
+		m2 += (v - mean) * (v - mean)
+		squares += v * v
+	}
+	if n > 0 {
+		abs /= float64(n)
+	}
+	d.checkDerived("mean", state.Mean, mean, abs)
+	d.checkDerived("M2", state.M2, m2, squares)
+}
+
+type stddevIndicator struct {
+	maIndicator
+	state stddevState
+}
+
+func (ind *stddevIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.state = newStddevState(ind.period)
+	return nil
+}
+
+func (ind *stddevIndicator) Update(data Data) (Point, bool) {
+	return ind.state.build(columnValue(data, ind.column))
+}
+
+func (ind *stddevIndicator) WarmupPeriod() int {
+	return ind.period
+}
+
+func (ind *stddevIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *stddevIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
+
+/* BOLLINGER BANDS */
+
+var bollingerColumns = []ColumnName{"upper", "middle", "lower", "percent_b", "bandwidth"}
+
+type bollingerIndicator struct {
+	maIndicator
+	multiplier float64
+	state      stddevState
+}
+
+func (ind *bollingerIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.multiplier = args.Float("multiplier")
+	ind.state = newStddevState(ind.period)
+	return nil
+}
+
+// the middle band is the SMA of the window, which is the mean kept by the standard deviation state
+func (ind *bollingerIndicator) UpdateVector(data Data) ([]float64, bool) {
+	value := columnValue(data, ind.column)
+	stddev, ready := ind.state.build(value)
+	if !ready {
+		return nil, false
+	}
+
+	middle := ind.state.Mean
+	upper := middle + ind.multiplier*stddev.Value
+	lower := middle - ind.multiplier*stddev.Value
+
+	percentB := 0.5
+	if upper != lower {
+		percentB = (value - lower) / (upper - lower)
+	}
+	bandwidth := 0.0
+	if middle != 0 {
+		bandwidth = (upper - lower) / middle
+	}
+	return []float64{upper, middle, lower, percentB, bandwidth}, true
+}
+
+func (ind *bollingerIndicator) WarmupPeriod() int {
+	return ind.period
+}
+
+func (ind *bollingerIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *bollingerIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
+}
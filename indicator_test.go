@@ -1,9 +1,13 @@
 package pcommon
 
 import (
+	"encoding/json"
+	"math"
+	"math/rand"
 	"testing"
 	"time"
 
+	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -45,20 +49,20 @@ func TestIndicatorBuilder(t *testing.T) {
 	assert.Equal(t, []byte(nil), b.PrevState(), "PrevState should be nil")
 	p, err := b.ComputeUnsafe(units[0])
 	assert.Equal(t, nil, err, "Error should be nil")
-	state := b.cachedParsedState.(*rsiState)
+	state := b.indicator.(*rsiIndicator).state
 	assert.Equal(t, units[0].Close, state.LastClose, "LastClose should be equal")
-	assert.Equal(t, p.Value, -1.00, "Value should be equal")
+	assert.Nil(t, p, "No point should be returned while warming up")
 
 	for i := 1; i < PERIOD_INT; i++ {
-		_, err := b.ComputeUnsafe(units[i])
+		p, err := b.ComputeUnsafe(units[i])
 		assert.Equal(t, nil, err, "Error should be nil")
-		assert.Equal(t, p.Value, -1.00, "Value should be equal")
-		state := b.cachedParsedState.(*rsiState)
+		assert.Nil(t, p, "No point should be returned while warming up")
+		state := b.indicator.(*rsiIndicator).state
 		assert.Equal(t, units[i].Close, state.LastClose, "LastClose should be equal")
 	}
 	p, err = b.ComputeUnsafe(units[PERIOD_INT])
 	assert.Equal(t, nil, err, "Error should be nil")
-	state = b.cachedParsedState.(*rsiState)
+	state = b.indicator.(*rsiIndicator).state
 	assert.Equal(t, units[PERIOD_INT].Close, state.LastClose, "LastClose should be equal")
 	assert.Equal(t, p.Value, 49.62440394539153, "Value should be equal")
 
@@ -105,20 +109,20 @@ func TestRSI2Builder(t *testing.T) {
 	p, err := b.ComputeUnsafe(units[0])
 	assert.Equal(t, nil, err, "Error should be nil")
 
-	state := b.cachedParsedState.(*rsiState)
+	state := b.indicator.(*rsiIndicator).state
 	assert.Equal(t, units[0].Close, state.LastClose, "LastClose should be equal")
-	assert.Equal(t, p.Value, -1.00, "Value should be equal")
+	assert.Nil(t, p, "No point should be returned while warming up")
 
 	for i := 1; i < PERIOD_INT; i++ {
-		_, err := b.ComputeUnsafe(units[i])
+		p, err := b.ComputeUnsafe(units[i])
 		assert.Equal(t, nil, err, "Error should be nil")
-		assert.Equal(t, p.Value, -1.00, "Value should be equal")
-		state := b.cachedParsedState.(*rsiState)
+		assert.Nil(t, p, "No point should be returned while warming up")
+		state := b.indicator.(*rsiIndicator).state
 		assert.Equal(t, units[i].Close, state.LastClose, "LastClose should be equal")
 	}
 	p, err = b.ComputeUnsafe(units[PERIOD_INT])
 	assert.Equal(t, nil, err, "Error should be nil")
-	state = b.cachedParsedState.(*rsiState)
+	state = b.indicator.(*rsiIndicator).state
 	assert.Equal(t, units[PERIOD_INT].Close, state.LastClose, "LastClose should be equal")
 	assert.Equal(t, p.Value, 49.62440394539153, "Value should be equal")
 }
@@ -166,7 +170,7 @@ func TestSMABuilder(t *testing.T) {
 		assert.Equal(t, nil, err, "Error should be nil")
 
 		if i+1 < PERIOD_INT {
-			assert.Equal(t, float64(-1), point.Value, "SMA value should be -1 before period is reached")
+			assert.Nil(t, point, "SMA should not return a point before period is reached")
 		} else {
 			// Manually calculate the expected SMA value for validation
 			sum := 0.0
@@ -177,6 +181,15 @@ func TestSMABuilder(t *testing.T) {
 			assert.Equal(t, expectedSMA, point.Value, "SMA value should be equal to the expected SMA")
 		}
 	}
+
+	// -1 is a valid value (ex: the SMA of a net flow), not a warm-up marker
+	b = NewIndicatorDataBuilder(Asset.SMA, nil, []string{"value", "2"}, -1)
+	point, err := b.ComputeUnsafe(Point{-1}.ToTime(units[0].Time))
+	assert.Nil(t, err)
+	assert.Nil(t, point)
+	point, err = b.ComputeUnsafe(Point{-1}.ToTime(units[1].Time))
+	assert.Nil(t, err)
+	assert.Equal(t, -1.0, point.Value)
 }
 
 func TestEMABuilder(t *testing.T) {
@@ -216,7 +229,7 @@ func TestEMABuilder(t *testing.T) {
 
 	b := NewIndicatorDataBuilder(Asset.EMA, nil, []string{COLUMN_NAME, PERIOD_STRING}, 7)
 	assert.Equal(t, []byte(nil), b.PrevState(), "PrevState should be nil")
-	state := newEmptyMAState(PERIOD_INT)
+	state := emaState{}
 
 	for i, unit := range units {
 		point, err := b.ComputeUnsafe(unit)
@@ -276,7 +289,7 @@ func TestHMABuilder(t *testing.T) {
 		point, err := b.ComputeUnsafe(unit)
 		assert.Equal(t, nil, err, "Error should be nil")
 		if i <= PERIOD_INT {
-			assert.Equal(t, float64(-1), point.Value, "HMA value should be -1 before period is reached")
+			assert.Nil(t, point, "HMA should not return a point before period is reached")
 		} else {
 			assert.Greater(t, point.Value, 65000.00, "HMA value should be greater than 0")
 		}
@@ -325,9 +338,1327 @@ func TestWMABuilder(t *testing.T) {
 		point, err := b.ComputeUnsafe(unit)
 		assert.Equal(t, nil, err, "Error should be nil")
 		if i+1 < PERIOD_INT {
-			assert.Equal(t, float64(-1), point.Value, "WMA value should be -1 before period is reached")
+			assert.Nil(t, point, "WMA should not return a point before period is reached")
 		} else {
 			assert.Greater(t, point.Value, 65000.00, "WMA value should be greater than 0")
 		}
 	}
 }
+
+func TestIndicatorStateResume(t *testing.T) {
+	values := []float64{
+		65532.01, 65531.50, 65530.98, 65529.45, 65528.12, 65527.65, 65526.78, 65525.89, 65524.47, 65523.55,
+		65522.33, 66433.97, 65520.75, 65519.43, 65518.21, 65517.99, 65516.88, 65515.65, 65514.42, 65513.18,
+		65512.77, 65511.56, 65510.34, 65509.22, 65508.15, 65507.03, 65506.89, 65505.77, 65504.66, 65503.54,
+	}
+	t0 := NewTimeUnit(1587607201)
+
+	for _, assetType := range []AssetType{Asset.RSI2, Asset.SMA, Asset.EMA, Asset.WMA, Asset.HMA, Asset.DEMA, Asset.TEMA, Asset.ZLEMA, Asset.STDDEV, Asset.ZSCORE, Asset.PERCENTILE_RANK, Asset.ROLLING_MIN, Asset.ROLLING_MAX, Asset.ROLLING_SUM} {
+		args := []string{"close", "9"}
+
+		continuous := NewIndicatorDataBuilder(assetType, nil, args, -1)
+		expected := []*Point{}
+		for i, v := range values {
+			p, err := continuous.ComputeUnsafe(NewUnit(v).ToTime(t0.Add(time.Duration(i) * time.Second)))
+			assert.Nil(t, err)
+			expected = append(expected, p)
+		}
+
+		// a new builder is created from the previous state every 7 values
+		var state []byte = nil
+		var b *IndicatorDataBuilder
+		for i, v := range values {
+			if i%7 == 0 {
+				b = NewIndicatorDataBuilder(assetType, state, args, -1)
+			}
+			p, err := b.ComputeUnsafe(NewUnit(v).ToTime(t0.Add(time.Duration(i) * time.Second)))
+			assert.Nil(t, err)
+			assert.Equal(t, expected[i], p, "%s value %d", assetType, i)
+			state = b.PrevState()
+		}
+
+		warmup := continuous.WarmupPeriod()
+		for i := range values {
+			if i+1 < warmup {
+				assert.Nil(t, expected[i], "%s should warm up during %d values", assetType, warmup)
+			} else {
+				assert.NotNil(t, expected[i], "%s should be ready after %d values", assetType, warmup)
+			}
+		}
+	}
+}
+
+func TestIndicatorLegacyJSONState(t *testing.T) {
+	values := []float64{
+		65532.01, 65531.50, 65530.98, 65529.45, 65528.12, 65527.65, 65526.78, 65525.89, 65524.47, 65523.55,
+		65522.33, 66433.97, 65520.75, 65519.43, 65518.21, 65517.99, 65516.88, 65515.65, 65514.42, 65513.18,
+	}
+	t0 := NewTimeUnit(1587607201)
+	const SPLIT = 12
+
+	// legacyState returns the JSON state the indicator would have been saved with before the binary format
+	legacyState := func(ind Indicator) []byte {
+		var state interface{}
+		wmaLegacy := func(s wmaState) legacyMAState {
+			return legacyMAState{WMABuff: s.Buffer.Values, Buffer: make([]float64, len(s.Buffer.Values)), Pos: s.Buffer.Pos, Count: s.Buffer.Count}
+		}
+		switch i := ind.(type) {
+		case *rsiIndicator:
+			state = i.state
+		case *smaIndicator:
+			state = legacyMAState{Sum: i.state.Sum, Buffer: i.state.Buffer.Values, WMABuff: make([]float64, i.period), Pos: i.state.Buffer.Pos, Count: i.state.Buffer.Count}
+		case *emaIndicator:
+			state = legacyMAState{EMA: i.state.EMA, Count: i.state.Count}
+		case *wmaIndicator:
+			state = wmaLegacy(i.state)
+		case *hmaIndicator:
+			alt := [][]byte{}
+			for _, sub := range []wmaState{i.state.Half, i.state.Full, i.state.Sqrt} {
+				b, _ := json.Marshal(wmaLegacy(sub))
+				alt = append(alt, b)
+			}
+			state = legacyMAState{Count: i.state.Full.Buffer.Count, AltState: alt}
+		}
+		b, _ := json.Marshal(state)
+		return b
+	}
+
+	for _, assetType := range []AssetType{Asset.RSI2, Asset.SMA, Asset.EMA, Asset.WMA, Asset.HMA} {
+		args := []string{"close", "9"}
+
+		b := NewIndicatorDataBuilder(assetType, nil, args, -1)
+		for i, v := range values[:SPLIT] {
+			_, err := b.ComputeUnsafe(NewUnit(v).ToTime(t0.Add(time.Duration(i) * time.Second)))
+			assert.Nil(t, err)
+		}
+		assert.Equal(t, indicatorStateMagic, b.PrevState()[0], "%s state should be binary", assetType)
+
+		legacy := NewIndicatorDataBuilder(assetType, legacyState(b.indicator), args, -1)
+		assert.Nil(t, legacy.initErr, "%s legacy state should be readable", assetType)
+
+		for i, v := range values[SPLIT:] {
+			data := NewUnit(v).ToTime(t0.Add(time.Duration(SPLIT+i) * time.Second))
+			expected, err := b.ComputeUnsafe(data)
+			assert.Nil(t, err)
+			p, err := legacy.ComputeUnsafe(data)
+			assert.Nil(t, err)
+			assert.Equal(t, expected.Value, p.Value, "%s value %d", assetType, SPLIT+i)
+		}
+	}
+
+	_, err := parseLegacyMAState([]byte("{"))
+	assert.NotNil(t, err)
+	d := newStateDecoder([]byte{indicatorStateMagic, indicatorStateVersion, 1, 2})
+	d.Float()
+	assert.NotNil(t, d.Err(), "truncated states should be rejected")
+}
+
+func TestCorruptedIndicatorState(t *testing.T) {
+	args := []string{"close", "9"}
+	corrupted := func(fields func(e *stateEncoder)) []byte {
+		e := newStateEncoder()
+		fields(e)
+		return e.Bytes()
+	}
+	ring := func(size int, pos, count int64) func(e *stateEncoder) {
+		return func(e *stateEncoder) {
+			e.Float(0)
+			e.Floats(make([]float64, size))
+			e.Int(pos)
+			e.Int(count)
+		}
+	}
+
+	states := map[string][]byte{
+		// a length overflowing length*8 must not pass the truncation check
+		"overflowing length":          append(corrupted(func(e *stateEncoder) { e.Float(0) }), 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x80, 0x20),
+		"empty ring":                  corrupted(ring(0, 0, 0)),
+		"ring of another period":      corrupted(ring(5, 0, 0)),
+		"position out of the ring":    corrupted(ring(9, 12, 12)),
+		"negative count":              corrupted(ring(9, 0, -9)),
+		"position and count mismatch": corrupted(ring(9, 2, 7)),
+	}
+	for name, state := range states {
+		b := NewIndicatorDataBuilder(Asset.SMA, state, args, -1)
+		assert.NotNil(t, b.initErr, name)
+	}
+
+	deque := func(values []float64, indexes []int64, count int64) []byte {
+		return corrupted(func(e *stateEncoder) {
+			e.Floats(values)
+			e.Ints(indexes)
+			e.Int(count)
+		})
+	}
+	assert.Nil(t, NewIndicatorDataBuilder(Asset.ROLLING_MAX, deque([]float64{5, 2}, []int64{3, 5}, 6), args, -1).initErr)
+	assert.Nil(t, NewIndicatorDataBuilder(Asset.ROLLING_MIN, deque([]float64{2, 5}, []int64{3, 5}, 6), args, -1).initErr)
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.ROLLING_MAX, deque([]float64{5, 2}, []int64{3, 6}, 6), args, -1).initErr, "index after the count")
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.ROLLING_MAX, deque([]float64{5, 2}, []int64{5, 3}, 6), args, -1).initErr, "unsorted indexes")
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.ROLLING_MAX, deque([]float64{5, 2}, []int64{1, 15}, 16), args, -1).initErr, "index out of the window")
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.ROLLING_MAX, deque([]float64{5, 2}, []int64{3, 4}, 6), args, -1).initErr, "last value missing")
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.ROLLING_MAX, deque([]float64{2, 5}, []int64{3, 5}, 6), args, -1).initErr, "unsorted values")
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.ROLLING_MAX, deque([]float64{5, 5}, []int64{3, 5}, 6), args, -1).initErr, "dominated value")
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.ROLLING_MIN, deque([]float64{5, 2}, []int64{3, 5}, 6), args, -1).initErr, "unsorted values")
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.ROLLING_MAX, deque([]float64{math.NaN(), 2}, []int64{3, 5}, 6), args, -1).initErr, "NaN value")
+
+	// the sums must match the values of their window
+	window := ringBuffer{Values: []float64{1, 2, 3, 4, 0, 0, 0, 0, 0}, Pos: 4, Count: 4}
+	sum := func(value float64) []byte {
+		return corrupted(func(e *stateEncoder) {
+			e.Ring(window)
+			e.Float(value)
+		})
+	}
+	assert.Nil(t, NewIndicatorDataBuilder(Asset.ROLLING_SUM, sum(10), args, -1).initErr)
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.ROLLING_SUM, sum(12), args, -1).initErr)
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.ROLLING_SUM, sum(math.NaN()), args, -1).initErr)
+
+	moments := func(mean, m2 float64) []byte {
+		return corrupted(func(e *stateEncoder) {
+			e.Float(mean)
+			e.Float(m2)
+			e.Ring(window)
+		})
+	}
+	assert.Nil(t, NewIndicatorDataBuilder(Asset.STDDEV, moments(2.5, 5), args, -1).initErr)
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.STDDEV, moments(3, 5), args, -1).initErr, "inconsistent mean")
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.STDDEV, moments(2.5, 50), args, -1).initErr, "inconsistent M2")
+
+	pairArgs := []string{"close", "9", "false"}
+	pair := func(b ringBuffer, c float64) []byte {
+		return corrupted(func(e *stateEncoder) {
+			e.Ring(window)
+			e.Ring(b)
+			for _, v := range []float64{2.5, 2.5, 5, 5, c, 0, 0} {
+				e.Float(v)
+			}
+			e.Bool(false)
+		})
+	}
+	assert.Nil(t, NewIndicatorDataBuilder(Asset.CORRELATION, pair(window, 5), pairArgs, -1).initErr)
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.CORRELATION, pair(window, -5), pairArgs, -1).initErr, "inconsistent co-moment")
+	shifted := ringBuffer{Values: []float64{1, 2, 3, 4, 0, 0, 0, 0, 0}, Pos: 4, Count: 13}
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.CORRELATION, pair(shifted, 5), pairArgs, -1).initErr, "unpaired values")
+
+	// NaN values cannot be ranked
+	rank := func(value float64) []byte {
+		return corrupted(func(e *stateEncoder) {
+			e.Ring(ringBuffer{Values: []float64{1, 2, value, 4, 0, 0, 0, 0, 0}, Pos: 4, Count: 4})
+		})
+	}
+	assert.Nil(t, NewIndicatorDataBuilder(Asset.PERCENTILE_RANK, rank(3), args, -1).initErr)
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.PERCENTILE_RANK, rank(math.NaN()), args, -1).initErr)
+
+	// the state of an indicator cannot be loaded with another period
+	b := NewIndicatorDataBuilder(Asset.HMA, nil, args, -1)
+	for i := 0; i < 20; i++ {
+		_, err := b.ComputeUnsafe(NewUnit(float64(100 + i)).ToTime(NewTimeUnit(1587607201).Add(time.Duration(i) * time.Second)))
+		assert.Nil(t, err)
+	}
+	assert.NotNil(t, NewIndicatorDataBuilder(Asset.HMA, b.PrevState(), []string{"close", "16"}, -1).initErr)
+}
+
+// FuzzIndicatorState checks that no state, however corrupted, makes an indicator panic.
+func FuzzIndicatorState(f *testing.F) {
+	assetTypes := []AssetType{Asset.SMA, Asset.WMA, Asset.HMA, Asset.STDDEV, Asset.PERCENTILE_RANK, Asset.ROLLING_MAX, Asset.ROLLING_SUM, Asset.KAMA}
+	args := []string{"close", "9"}
+	t0 := NewTimeUnit(1587607201)
+	for _, assetType := range assetTypes {
+		b := NewIndicatorDataBuilder(assetType, nil, args, -1)
+		for i := 0; i < 12; i++ {
+			b.ComputeUnsafe(NewUnit(float64(100 + i%5)).ToTime(t0.Add(time.Duration(i) * time.Second)))
+		}
+		f.Add(b.PrevState())
+	}
+
+	f.Fuzz(func(t *testing.T, state []byte) {
+		for _, assetType := range assetTypes {
+			b := NewIndicatorDataBuilder(assetType, state, args, -1)
+			if b.initErr != nil {
+				continue
+			}
+			for i := 0; i < 20; i++ {
+				if _, err := b.ComputeUnsafe(NewUnit(float64(100 + i)).ToTime(t0.Add(time.Duration(i) * time.Second))); err != nil {
+					t.Fatalf("%s: %s", assetType, err)
+				}
+			}
+		}
+	})
+}
+
+func TestIndicatorComputeBatch(t *testing.T) {
+	t0 := NewTimeUnit(1587607201)
+	units := UnitTimeArray{}
+	for i := 0; i < 200; i++ {
+		if i%17 == 5 {
+			// candle without trade
+			units = append(units, UnitTime{Time: t0.Add(time.Duration(i) * time.Second)})
+			continue
+		}
+		units = append(units, NewUnit(65000+float64(i%13)*3.5-float64(i%7)*2.25).ToTime(t0.Add(time.Duration(i)*time.Second)))
+	}
+
+	for _, assetType := range []AssetType{Asset.RSI2, Asset.SMA, Asset.EMA, Asset.WMA, Asset.HMA, Asset.DEMA, Asset.TEMA, Asset.ZLEMA, Asset.STDDEV, Asset.ZSCORE, Asset.PERCENTILE_RANK, Asset.ROLLING_MIN, Asset.ROLLING_MAX, Asset.ROLLING_SUM} {
+		args := []string{"close", "14"}
+
+		// empty candles go through both paths the same way
+		streaming := NewIndicatorDataBuilder(assetType, nil, args, 4)
+		expected := PointTimeArray{}
+		for _, unit := range units {
+			p, err := streaming.ComputeUnsafe(unit)
+			assert.Nil(t, err)
+			if p != nil {
+				expected = append(expected, p.ToTime(unit.Time))
+			}
+		}
+
+		// the batch is split in two to check the state is carried over
+		b := NewIndicatorDataBuilder(assetType, nil, args, 4)
+		first, state, err := b.ComputeBatch(units[:90])
+		assert.Nil(t, err)
+		b = NewIndicatorDataBuilder(assetType, state, args, 4)
+		second, state, err := b.ComputeBatch(units[90:])
+		assert.Nil(t, err)
+
+		assert.Equal(t, expected, append(first, second...), "%s batch should match streaming", assetType)
+		assert.Equal(t, streaming.PrevState(), state)
+	}
+}
+
+func benchmarkUnits(n int) UnitTimeArray {
+	t0 := NewTimeUnit(1587607201)
+	units := make(UnitTimeArray, n)
+	for i := range units {
+		units[i] = NewUnit(65000 + float64(i%13)*3.5).ToTime(t0.Add(time.Duration(i) * time.Second))
+	}
+	return units
+}
+
+func BenchmarkHMAComputeUnsafe(b *testing.B) {
+	units := benchmarkUnits(b.N)
+	builder := NewIndicatorDataBuilder(Asset.HMA, nil, []string{"close", "50"}, 4)
+	b.ResetTimer()
+	for _, unit := range units {
+		builder.ComputeUnsafe(unit)
+	}
+}
+
+func BenchmarkHMAComputeBatch(b *testing.B) {
+	units := benchmarkUnits(b.N)
+	builder := NewIndicatorDataBuilder(Asset.HMA, nil, []string{"close", "50"}, 4)
+	b.ResetTimer()
+	builder.ComputeBatch(units)
+}
+
+// testVectorIndicator checks a vector indicator gives the same values when computed continuously,
+// resumed from its state every 7 values, or in batch, and returns the continuous values.
+func testVectorIndicator(t *testing.T, assetType AssetType, args []string, units UnitTimeArray) []*Vector {
+	continuous := NewIndicatorDataBuilder(assetType, nil, args, -1)
+	expected := []*Vector{}
+	batchExpected := VectorTimeArray{}
+	for _, unit := range units {
+		v, err := continuous.ComputeVectorUnsafe(unit)
+		assert.Nil(t, err)
+		expected = append(expected, v)
+		if v != nil {
+			batchExpected = append(batchExpected, v.ToTime(unit.Time))
+		}
+	}
+
+	var state []byte = nil
+	var b *IndicatorDataBuilder
+	for i, unit := range units {
+		if i%7 == 0 {
+			b = NewIndicatorDataBuilder(assetType, state, args, -1)
+		}
+		v, err := b.ComputeVectorUnsafe(unit)
+		assert.Nil(t, err)
+		assert.Equal(t, expected[i], v, "%s value %d", assetType, i)
+		state = b.PrevState()
+	}
+
+	batch, state, err := NewIndicatorDataBuilder(assetType, nil, args, -1).ComputeVectorBatch(units)
+	assert.Nil(t, err)
+	assert.Equal(t, batchExpected, batch, "%s batch should match streaming", assetType)
+	assert.Equal(t, continuous.PrevState(), state)
+	return expected
+}
+
+func TestMACDBuilder(t *testing.T) {
+	t0 := NewTimeUnit(1587607201)
+	units := UnitTimeArray{}
+	for i := 0; i < 120; i++ {
+		units = append(units, NewUnit(65000+float64(i%13)*3.5-float64(i%7)*2.25+float64(i)*0.8).ToTime(t0.Add(time.Duration(i)*time.Second)))
+	}
+
+	const FAST, SLOW, SIGNAL = 5, 12, 4
+	values := testVectorIndicator(t, Asset.MACD, []string{"close", "5", "12", "4"}, units)
+
+	ema := func(prev float64, value float64, period int) float64 {
+		k := 2.0 / float64(period+1)
+		return value*k + prev*(1-k)
+	}
+	fast, slow, signal := units[0].Close, units[0].Close, 0.0
+	for i, unit := range units {
+		if i > 0 {
+			fast = ema(fast, unit.Close, FAST)
+			slow = ema(slow, unit.Close, SLOW)
+		}
+		if i+1 < SLOW {
+			assert.Nil(t, values[i])
+			continue
+		}
+		macd := fast - slow
+		if i+1 == SLOW {
+			signal = macd
+		} else {
+			signal = ema(signal, macd, SIGNAL)
+		}
+		if i+1 < SLOW+SIGNAL-1 {
+			assert.Nil(t, values[i], "MACD should warm up during %d values", SLOW+SIGNAL-1)
+			continue
+		}
+		assert.InDeltaSlice(t, []float64{macd, signal, macd - signal}, values[i].Values, 1e-9)
+		assert.Equal(t, macdColumns, values[i].Columns)
+	}
+
+	b := NewIndicatorDataBuilder(Asset.MACD, nil, []string{"close", "5", "12", "4"}, -1)
+	assert.Equal(t, SLOW+SIGNAL-1, b.WarmupPeriod())
+
+	price := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.SPOT_PRICE}
+	macd := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.MACD, Dependencies: []AssetAddress{price.BuildAddress()}, Arguments: []string{"close", "12", "26", "9"}}
+	assert.Nil(t, macd.IsValid())
+	macd.Arguments = []string{"close", "26", "12", "9"}
+	assert.EqualError(t, macd.IsValid(), "invalid argument for macd: fast period must be lower than slow period")
+}
+
+func TestBollingerBuilder(t *testing.T) {
+	t0 := NewTimeUnit(1587607201)
+	units := UnitTimeArray{}
+	for i := 0; i < 500; i++ {
+		// large prices with small moves, where a sum of squares loses precision
+		units = append(units, NewUnit(1e9+float64(i%13)*0.35-float64(i%7)*0.225).ToTime(t0.Add(time.Duration(i)*time.Second)))
+	}
+
+	const PERIOD = 20
+	bands := testVectorIndicator(t, Asset.BOLLINGER, []string{"close", "20", "2.5"}, units)
+	stddev := NewIndicatorDataBuilder(Asset.STDDEV, nil, []string{"close", "20"}, -1)
+
+	for i, unit := range units {
+		p, err := stddev.ComputeUnsafe(unit)
+		assert.Nil(t, err)
+		if i+1 < PERIOD {
+			assert.Nil(t, bands[i])
+			assert.Nil(t, p)
+			continue
+		}
+
+		window := lo.Map(units[i+1-PERIOD:i+1], func(u UnitTime, _ int) float64 { return u.Close })
+		// CalculateStandardDeviation is the sample standard deviation, the bands use the population one
+		expectedStddev := Math.CalculateStandardDeviation(window) * math.Sqrt(float64(PERIOD-1)/PERIOD)
+		expectedMean := lo.Sum(window) / PERIOD
+		assert.InDelta(t, expectedStddev, p.Value, 1e-6, "stddev value %d", i)
+
+		upper, lower := expectedMean+2.5*expectedStddev, expectedMean-2.5*expectedStddev
+		assert.InDeltaSlice(t, []float64{upper, expectedMean, lower}, bands[i].Values[:3], 1e-5, "bands value %d", i)
+		assert.InDelta(t, (unit.Close-lower)/(upper-lower), bands[i].Values[3], 1e-3, "%%B value %d", i)
+		assert.InDelta(t, (upper-lower)/expectedMean, bands[i].Values[4], 1e-12, "bandwidth value %d", i)
+	}
+}
+
+func TestCandleIndicators(t *testing.T) {
+	t0 := NewTimeUnit(1587607201)
+	units := UnitTimeArray{}
+	for i := 0; i < 150; i++ {
+		tu := t0.Add(time.Duration(i) * time.Second)
+		if i%11 == 4 {
+			// candle without trade
+			units = append(units, UnitTime{Time: tu})
+			continue
+		}
+		close := 65000 + float64(i%13)*3.5 - float64(i%7)*2.25 + float64(i)*0.4
+		units = append(units, Unit{Open: close - 1, High: close + float64(i%5) + 0.5, Low: close - float64(i%3) - 0.5, Close: close, Count: 3}.ToTime(tu))
+	}
+	candles := lo.Filter(units, func(u UnitTime, _ int) bool { return !u.IsEmpty() })
+
+	const PERIOD = 10
+	compute := func(assetType AssetType, args []string) []*Point {
+		b := NewIndicatorDataBuilder(assetType, nil, args, -1)
+		ret := []*Point{}
+		for _, unit := range units {
+			p, err := b.ComputeUnsafe(unit)
+			assert.Nil(t, err)
+			if !unit.IsEmpty() {
+				ret = append(ret, p)
+			} else {
+				assert.Nil(t, p, "empty candles should be skipped")
+			}
+		}
+		return ret
+	}
+	tr := compute(Asset.TR, nil)
+	atr := compute(Asset.ATR, []string{"10"})
+	willr := compute(Asset.WILLIAMS_R, []string{"10"})
+	cci := compute(Asset.CCI, []string{"10"})
+	stoch := lo.Filter(testVectorIndicator(t, Asset.STOCHASTIC, []string{"10", "3"}, units), func(_ *Vector, i int) bool {
+		return !units[i].IsEmpty()
+	})
+
+	expectedTR := make([]float64, len(candles))
+	expectedK := []float64{}
+	expectedATR := 0.0
+	for i, c := range candles {
+		expectedTR[i] = c.High - c.Low
+		if i > 0 {
+			prev := candles[i-1].Close
+			expectedTR[i] = math.Max(expectedTR[i], math.Max(math.Abs(c.High-prev), math.Abs(c.Low-prev)))
+		}
+		assert.InDelta(t, expectedTR[i], tr[i].Value, 1e-9)
+
+		if i+1 < PERIOD {
+			assert.Nil(t, atr[i])
+			assert.Nil(t, willr[i])
+			assert.Nil(t, cci[i])
+			assert.Nil(t, stoch[i])
+			continue
+		}
+		if i+1 == PERIOD {
+			expectedATR = lo.Sum(expectedTR[:PERIOD]) / PERIOD
+		} else {
+			expectedATR = (expectedATR*(PERIOD-1) + expectedTR[i]) / PERIOD
+		}
+		assert.InDelta(t, expectedATR, atr[i].Value, 1e-9, "ATR value %d", i)
+
+		window := candles[i+1-PERIOD : i+1]
+		highest := lo.MaxBy(window, func(a, b UnitTime) bool { return a.High > b.High }).High
+		lowest := lo.MinBy(window, func(a, b UnitTime) bool { return a.Low < b.Low }).Low
+		assert.InDelta(t, -100*(highest-c.Close)/(highest-lowest), willr[i].Value, 1e-9, "Williams %%R value %d", i)
+
+		expectedK = append(expectedK, 100*(c.Close-lowest)/(highest-lowest))
+		if len(expectedK) < 3 {
+			assert.Nil(t, stoch[i])
+		} else {
+			assert.InDeltaSlice(t, []float64{expectedK[len(expectedK)-1], lo.Sum(expectedK[len(expectedK)-3:]) / 3}, stoch[i].Values, 1e-9, "stochastic value %d", i)
+		}
+
+		typicals := lo.Map(window, func(u UnitTime, _ int) float64 { return (u.High + u.Low + u.Close) / 3 })
+		mean := lo.Sum(typicals) / PERIOD
+		deviation := lo.Sum(lo.Map(typicals, func(v float64, _ int) float64 { return math.Abs(v - mean) })) / PERIOD
+		assert.InDelta(t, (typicals[PERIOD-1]-mean)/(0.015*deviation), cci[i].Value, 1e-6, "CCI value %d", i)
+	}
+
+	_, err := NewIndicatorDataBuilder(Asset.ATR, nil, []string{"10"}, -1).ComputeUnsafe(NewQuantity(10).ToTime(t0))
+	assert.NotNil(t, err, "candle indicators need a unit")
+}
+
+func TestVWABuilder(t *testing.T) {
+	t0 := NewTimeUnit(1587607201)
+	prices := UnitTimeArray{}
+	volumes := QuantityTimeArray{}
+	for i := 0; i < 100; i++ {
+		tu := t0.Add(time.Duration(i) * time.Second)
+		if i%9 != 3 {
+			prices = append(prices, NewUnit(65000+float64(i%13)*3.5-float64(i%7)*2.25).ToTime(tu))
+		}
+		if i%10 != 7 {
+			// buys and sells
+			volumes = append(volumes, QuantityTime{Quantity{Plus: float64(i%5) + 0.5, Minus: float64(i % 3), PlusCount: 1, MinusCount: 1}, tu})
+		}
+	}
+
+	rows := AlignDataLists(prices, volumes)
+	for _, row := range rows {
+		assert.Equal(t, row[0].GetTime(), row[1].GetTime())
+		i := int(row[0].GetTime().ToTime().Sub(t0.ToTime()) / time.Second)
+		assert.True(t, i%9 != 3 && i%10 != 7)
+	}
+	assert.Equal(t, 100-11-10+1, len(rows), "only the times present in both lists are kept")
+
+	// on the minimum timeframe, the VWAP of a candle is its price
+	b := NewIndicatorDataBuilder(Asset.WA, nil, []string{"close"}, -1)
+	points := PointTimeArray{}
+	for _, row := range rows {
+		p, err := b.ComputeUnsafe(row...)
+		assert.Nil(t, err)
+		assert.Equal(t, row[0].(UnitTime).Close, p.Value)
+		points = append(points, p.ToTime(row[0].GetTime()))
+	}
+	p, err := b.ComputeUnsafe(NewUnit(65000).ToTime(t0), QuantityTime{Time: t0})
+	assert.Nil(t, err)
+	assert.Nil(t, p, "a candle without volume has no VWAP")
+
+	batch, state, err := NewIndicatorDataBuilder(Asset.WA, nil, []string{"close"}, -1).ComputeBatchAll(prices, volumes)
+	assert.Nil(t, err)
+	assert.Nil(t, state)
+	assert.Equal(t, points, batch)
+
+	// on a candle, the prices of the minimum timeframe are weighted by their volume
+	p, err = b.ComputeCandleUnsafe(t0, rows[:3])
+	assert.Nil(t, err)
+	sumPriceVolume, sumVolume := 0.0, 0.0
+	for _, r := range rows[:3] {
+		volume := r[1].(QuantityTime).Plus + r[1].(QuantityTime).Minus
+		sumPriceVolume += r[0].(UnitTime).Close * volume
+		sumVolume += volume
+	}
+	assert.InDelta(t, sumPriceVolume/sumVolume, p.Value, 1e-9)
+	_, err = NewIndicatorDataBuilder(Asset.VWMA, nil, []string{"close", "8"}, -1).ComputeCandleUnsafe(t0, rows[:3])
+	assert.NotNil(t, err, "VWMA is not indexed on the minimum timeframe")
+
+	_, err = b.ComputeUnsafe(prices[0], volumes[1])
+	assert.EqualError(t, err, "dependency values must have the same time")
+	_, err = b.ComputeUnsafe(prices[0])
+	assert.NotNil(t, err, "WA needs a price and a volume")
+
+	price := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.SPOT_PRICE}
+	volume := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.SPOT_VOLUME}
+	wa := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.WA, Dependencies: []AssetAddress{price.BuildAddress(), volume.BuildAddress()}, Arguments: []string{"close"}}
+	assert.Nil(t, wa.IsValid())
+	wa.Dependencies = []AssetAddress{volume.BuildAddress(), price.BuildAddress()}
+	assert.NotNil(t, wa.IsValid())
+}
+
+func TestVolumeIndicators(t *testing.T) {
+	t0 := NewTimeUnit(1587607201)
+	prices := UnitTimeArray{}
+	volumes := QuantityTimeArray{}
+	for i := 0; i < 80; i++ {
+		tu := t0.Add(time.Duration(i) * time.Second)
+		close := 65000 + float64(i%13)*3.5 - float64(i%7)*2.25
+		prices = append(prices, Unit{Open: close, High: close + float64(i%4) + 1, Low: close - float64(i%3) - 1, Close: close, Count: 5}.ToTime(tu))
+		volumes = append(volumes, QuantityTime{Quantity{Plus: float64(i%5) + 0.5, Minus: float64(i%3) + 0.25, PlusCount: 2, MinusCount: 3}, tu})
+	}
+	compute := func(assetType AssetType, args ...string) []float64 {
+		points, _, err := NewIndicatorDataBuilder(assetType, nil, args, -1).ComputeBatchAll(prices, volumes)
+		assert.Nil(t, err)
+		return lo.Map(points, func(p PointTime, _ int) float64 { return p.Value })
+	}
+	volume := func(i int) float64 { return volumes[i].Plus + volumes[i].Minus }
+	typical := func(i int) float64 { return (prices[i].High + prices[i].Low + prices[i].Close) / 3 }
+	clv := func(i int) float64 {
+		u := prices[i]
+		return ((u.Close - u.Low) - (u.High - u.Close)) / (u.High - u.Low)
+	}
+
+	obv, obvTaker, ad := 0.0, 0.0, 0.0
+	obvValues, obvTakerValues, adValues := []float64{}, []float64{}, []float64{}
+	for i := range prices {
+		obvTaker += volumes[i].Plus - volumes[i].Minus
+		obvTakerValues = append(obvTakerValues, obvTaker)
+		ad += clv(i) * volume(i)
+		adValues = append(adValues, ad)
+		if i == 0 {
+			continue
+		}
+		if prices[i].Close > prices[i-1].Close {
+			obv += volume(i)
+		} else if prices[i].Close < prices[i-1].Close {
+			obv -= volume(i)
+		}
+		obvValues = append(obvValues, obv)
+	}
+	assert.InDeltaSlice(t, obvValues, compute(Asset.OBV, "close"), 1e-9)
+	assert.InDeltaSlice(t, obvTakerValues, compute(Asset.OBV, "taker"), 1e-9)
+	assert.InDeltaSlice(t, adValues, compute(Asset.AD), 1e-9)
+
+	const PERIOD = 10
+	mfiValues, mfiTakerValues, cmfValues := []float64{}, []float64{}, []float64{}
+	for i := PERIOD - 1; i < len(prices); i++ {
+		positive, negative, positiveTaker, negativeTaker, moneyFlowVolume, totalVolume := 0.0, 0.0, 0.0, 0.0, 0.0, 0.0
+		for j := i + 1 - PERIOD; j <= i; j++ {
+			positiveTaker += typical(j) * volumes[j].Plus
+			negativeTaker += typical(j) * volumes[j].Minus
+			moneyFlowVolume += clv(j) * volume(j)
+			totalVolume += volume(j)
+			if j > 0 && typical(j) > typical(j-1) {
+				positive += typical(j) * volume(j)
+			} else if j > 0 && typical(j) < typical(j-1) {
+				negative += typical(j) * volume(j)
+			}
+		}
+		if i >= PERIOD {
+			mfiValues = append(mfiValues, 100*positive/(positive+negative))
+		}
+		mfiTakerValues = append(mfiTakerValues, 100*positiveTaker/(positiveTaker+negativeTaker))
+		cmfValues = append(cmfValues, moneyFlowVolume/totalVolume)
+	}
+	assert.InDeltaSlice(t, mfiValues, compute(Asset.MFI, "10", "close"), 1e-9)
+	assert.InDeltaSlice(t, mfiTakerValues, compute(Asset.MFI, "10", "taker"), 1e-9)
+	assert.InDeltaSlice(t, cmfValues, compute(Asset.CMF, "10"), 1e-9)
+
+	price := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.SPOT_PRICE}
+	volumeAddr := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.SPOT_VOLUME}
+	mfi := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.MFI, Dependencies: []AssetAddress{price.BuildAddress(), volumeAddr.BuildAddress()}, Arguments: []string{"14", "taker"}}
+	assert.Nil(t, mfi.IsValid())
+	mfi.Arguments = []string{"14", "open"}
+	assert.NotNil(t, mfi.IsValid(), "method must be close or taker")
+
+	mfi.Arguments = []string{"14", "close"}
+	volumeAddr.SetID = []string{"eth", "usdt"}
+	mfi.Dependencies = []AssetAddress{price.BuildAddress(), volumeAddr.BuildAddress()}
+	assert.EqualError(t, mfi.IsValid(), "invalid dependency 1 for mfi: spot_volume belongs to another set")
+}
+
+func TestExtendedMovingAverages(t *testing.T) {
+	t0 := NewTimeUnit(1587607201)
+	units := UnitTimeArray{}
+	for i := 0; i < 120; i++ {
+		units = append(units, NewUnit(65000+float64(i%13)*3.5-float64(i%7)*2.25+float64(i)*0.8).ToTime(t0.Add(time.Duration(i)*time.Second)))
+	}
+	closes := lo.Map(units, func(u UnitTime, _ int) float64 { return u.Close })
+	compute := func(assetType AssetType, args ...string) []float64 {
+		b := NewIndicatorDataBuilder(assetType, nil, args, -1)
+		points, _, err := b.ComputeBatch(units)
+		assert.Nil(t, err)
+		return lo.Map(points, func(p PointTime, _ int) float64 { return p.Value })
+	}
+	ema := func(values []float64, period int) []float64 {
+		k := 2.0 / float64(period+1)
+		ret := []float64{values[0]}
+		for _, v := range values[1:] {
+			ret = append(ret, v*k+ret[len(ret)-1]*(1-k))
+		}
+		return ret
+	}
+
+	const PERIOD = 9
+	ema1 := ema(closes, PERIOD)
+	ema2 := ema(ema1, PERIOD)
+	ema3 := ema(ema2, PERIOD)
+	dema := lo.Map(ema1, func(v float64, i int) float64 { return 2*v - ema2[i] })
+	tema := lo.Map(ema1, func(v float64, i int) float64 { return 3*v - 3*ema2[i] + ema3[i] })
+	assert.InDeltaSlice(t, dema, compute(Asset.DEMA, "close", "9"), 1e-9)
+	assert.InDeltaSlice(t, tema, compute(Asset.TEMA, "close", "9"), 1e-9)
+
+	const LAG = (PERIOD - 1) / 2
+	corrected := []float64{}
+	for i := LAG; i < len(closes); i++ {
+		corrected = append(corrected, 2*closes[i]-closes[i-LAG])
+	}
+	assert.InDeltaSlice(t, ema(corrected, PERIOD), compute(Asset.ZLEMA, "close", "9"), 1e-9)
+
+	const FAST, SLOW = 2, 30
+	kama := []float64{closes[PERIOD]}
+	for i := PERIOD + 1; i < len(closes); i++ {
+		volatility := 0.0
+		for j := i - PERIOD + 1; j <= i; j++ {
+			volatility += math.Abs(closes[j] - closes[j-1])
+		}
+		efficiency := math.Abs(closes[i]-closes[i-PERIOD]) / volatility
+		smoothing := math.Pow(efficiency*(2.0/(FAST+1)-2.0/(SLOW+1))+2.0/(SLOW+1), 2)
+		kama = append(kama, kama[len(kama)-1]+smoothing*(closes[i]-kama[len(kama)-1]))
+	}
+	assert.InDeltaSlice(t, kama, compute(Asset.KAMA, "close", "9", "2", "30"), 1e-9)
+	assert.Equal(t, PERIOD+1, NewIndicatorDataBuilder(Asset.KAMA, nil, []string{"close", "9", "2", "30"}, -1).WarmupPeriod())
+
+	// VWMA weights the values of the window by their volume
+	volumes := QuantityTimeArray{}
+	for i, u := range units {
+		volumes = append(volumes, QuantityTime{Quantity{Plus: float64(i%5) + 0.5, PlusCount: 1}, u.Time})
+	}
+	vwma, _, err := NewIndicatorDataBuilder(Asset.VWMA, nil, []string{"close", "9"}, -1).ComputeBatchAll(units, volumes)
+	assert.Nil(t, err)
+	assert.Equal(t, len(units)-PERIOD+1, len(vwma))
+	for i := PERIOD - 1; i < len(units); i++ {
+		sumPriceVolume, sumVolume := 0.0, 0.0
+		for j := i + 1 - PERIOD; j <= i; j++ {
+			sumPriceVolume += units[j].Close * volumes[j].Plus
+			sumVolume += volumes[j].Plus
+		}
+		assert.InDelta(t, sumPriceVolume/sumVolume, vwma[i+1-PERIOD].Value, 1e-9, "VWMA value %d", i)
+	}
+}
+
+func TestRollingStatistics(t *testing.T) {
+	t0 := NewTimeUnit(1587607201)
+	units := UnitTimeArray{}
+	for i := 0; i < 150; i++ {
+		// repeated values to check the ties of the percentile rank
+		units = append(units, NewUnit(65000+float64(i%13)*3.5-float64(i%7)*2.5).ToTime(t0.Add(time.Duration(i)*time.Second)))
+	}
+	compute := func(assetType AssetType) []float64 {
+		points, _, err := NewIndicatorDataBuilder(assetType, nil, []string{"close", "12"}, -1).ComputeBatch(units)
+		assert.Nil(t, err)
+		return lo.Map(points, func(p PointTime, _ int) float64 { return p.Value })
+	}
+	zscore := compute(Asset.ZSCORE)
+	rank := compute(Asset.PERCENTILE_RANK)
+	min := compute(Asset.ROLLING_MIN)
+	max := compute(Asset.ROLLING_MAX)
+	sum := compute(Asset.ROLLING_SUM)
+
+	const PERIOD = 12
+	for i := PERIOD - 1; i < len(units); i++ {
+		window := lo.Map(units[i+1-PERIOD:i+1], func(u UnitTime, _ int) float64 { return u.Close })
+		value := window[PERIOD-1]
+		mean := lo.Sum(window) / PERIOD
+		stddev := Math.CalculateStandardDeviation(window) * math.Sqrt(float64(PERIOD-1)/PERIOD)
+		lower := lo.CountBy(window, func(v float64) bool { return v < value })
+		equal := lo.Count(window, value)
+
+		j := i + 1 - PERIOD
+		assert.InDelta(t, (value-mean)/stddev, zscore[j], 1e-6, "z-score value %d", i)
+		assert.InDelta(t, 100*(float64(lower)+float64(equal-1)/2)/(PERIOD-1), rank[j], 1e-9, "percentile rank value %d", i)
+		assert.Equal(t, lo.Min(window), min[j])
+		assert.Equal(t, lo.Max(window), max[j])
+		assert.InDelta(t, lo.Sum(window), sum[j], 1e-6)
+	}
+}
+
+func TestRankTree(t *testing.T) {
+	// few distinct values to have many ties
+	values := []float64{math.Inf(-1), math.Inf(1), -0.5, 0}
+	r := rand.New(rand.NewSource(42))
+	for i := 0; i < 2000; i++ {
+		values = append(values, float64(r.Intn(40))-20)
+	}
+
+	const PERIOD = 25
+	tree := newRankTree(PERIOD)
+	for i, v := range values {
+		if i >= PERIOD {
+			tree.Remove(values[i-PERIOD])
+		}
+		tree.Insert(v)
+
+		window := values[lo.Max([]int{0, i + 1 - PERIOD}) : i+1]
+		assert.Equal(t, len(window), tree.Len())
+		for _, x := range []float64{v, window[0], float64(r.Intn(44)) - 22, math.Inf(1)} {
+			lower, equal := tree.Rank(x)
+			assert.Equal(t, lo.CountBy(window, func(w float64) bool { return w < x }), lower, "values lower than %v at %d", x, i)
+			assert.Equal(t, lo.Count(window, x), equal, "values equal to %v at %d", x, i)
+		}
+	}
+	// removed nodes are reused
+	assert.LessOrEqual(t, len(tree.nodes), PERIOD)
+
+	// NaN values are skipped
+	b := NewIndicatorDataBuilder(Asset.PERCENTILE_RANK, nil, []string{"close", "3"}, -1)
+	t0 := NewTimeUnit(1587607201)
+	closes := []float64{1, 2, math.NaN(), 3, 2}
+	points := []*Point{}
+	for i, c := range closes {
+		p, err := b.ComputeUnsafe(NewUnit(c).ToTime(t0.Add(time.Duration(i) * time.Second)))
+		assert.Nil(t, err)
+		points = append(points, p)
+	}
+	assert.Nil(t, points[2])
+	assert.Equal(t, 100.0, points[3].Value)
+	assert.Equal(t, 25.0, points[4].Value)
+}
+
+func TestPairIndicators(t *testing.T) {
+	t0 := NewTimeUnit(1587607201)
+	btc := UnitTimeArray{}
+	eth := UnitTimeArray{}
+	for i := 0; i < 120; i++ {
+		tu := t0.Add(time.Duration(i) * time.Second)
+		btcClose := 65000 + float64(i%13)*35 - float64(i%7)*22.5
+		btc = append(btc, NewUnit(btcClose).ToTime(tu))
+		// eth misses a value, the pairs are aligned on time
+		if i != 40 {
+			eth = append(eth, NewUnit(3200+btcClose/40+float64(i%5)*1.5).ToTime(tu))
+		}
+	}
+	compute := func(assetType AssetType, returns string) []float64 {
+		points, _, err := NewIndicatorDataBuilder(assetType, nil, []string{"close", "20", returns}, -1).ComputeBatchAll(eth, btc)
+		assert.Nil(t, err)
+		return lo.Map(points, func(p PointTime, _ int) float64 { return p.Value })
+	}
+
+	pairs := AlignDataLists(eth, btc)
+	const PERIOD = 20
+	for _, returns := range []string{"false", "true"} {
+		a := lo.Map(pairs, func(p []Data, _ int) float64 { return p[0].(UnitTime).Close })
+		b := lo.Map(pairs, func(p []Data, _ int) float64 { return p[1].(UnitTime).Close })
+		if returns == "true" {
+			change := func(values []float64) []float64 {
+				return lo.Map(values[1:], func(v float64, i int) float64 { return v/values[i] - 1 })
+			}
+			a, b = change(a), change(b)
+		}
+
+		correlation := compute(Asset.CORRELATION, returns)
+		covariance := compute(Asset.COVARIANCE, returns)
+		beta := compute(Asset.BETA, returns)
+		assert.Equal(t, len(a)-PERIOD+1, len(correlation))
+
+		for i := PERIOD - 1; i < len(a); i++ {
+			wa, wb := a[i+1-PERIOD:i+1], b[i+1-PERIOD:i+1]
+			meanA, meanB := lo.Sum(wa)/PERIOD, lo.Sum(wb)/PERIOD
+			varA, varB, cov := 0.0, 0.0, 0.0
+			for j := range wa {
+				varA += (wa[j] - meanA) * (wa[j] - meanA)
+				varB += (wb[j] - meanB) * (wb[j] - meanB)
+				cov += (wa[j] - meanA) * (wb[j] - meanB)
+			}
+			j := i + 1 - PERIOD
+			assert.InDelta(t, cov/math.Sqrt(varA*varB), correlation[j], 1e-6, "correlation %s value %d", returns, i)
+			assert.InDelta(t, cov/varB, beta[j], 1e-6, "beta %s value %d", returns, i)
+			assert.InDelta(t, cov/PERIOD, covariance[j], math.Abs(cov/PERIOD)*1e-6, "covariance %s value %d", returns, i)
+		}
+	}
+
+	btcPrice := AssetAddressParsed{SetID: []string{"btc", "usdt"}, AssetType: Asset.SPOT_PRICE}.BuildAddress()
+	ethPrice := AssetAddressParsed{SetID: []string{"eth", "usdt"}, AssetType: Asset.SPOT_PRICE}.BuildAddress()
+	beta := AssetAddressParsed{SetID: []string{"eth", "usdt"}, AssetType: Asset.BETA, Dependencies: []AssetAddress{ethPrice, btcPrice}, Arguments: []string{"close", "30", "true"}}
+	assert.Nil(t, beta.IsValid())
+
+	// the first dependency belongs to the set of the asset
+	beta.Dependencies = []AssetAddress{btcPrice, ethPrice}
+	assert.NotNil(t, beta.IsValid())
+	beta.Dependencies = []AssetAddress{ethPrice, ethPrice}
+	assert.NotNil(t, beta.IsValid())
+
+	// other assets keep their dependencies in their set
+	sma := AssetAddressParsed{SetID: []string{"eth", "usdt"}, AssetType: Asset.SMA, Dependencies: []AssetAddress{btcPrice}, Arguments: []string{"close", "14"}}
+	assert.NotNil(t, sma.IsValid())
+
+	beta.Dependencies = []AssetAddress{ethPrice, btcPrice}
+	betaAddr := beta.BuildAddress()
+	graph, err := NewAssetGraph([]AssetAddress{ethPrice, betaAddr})
+	assert.Nil(t, err)
+	assert.Nil(t, graph.Check())
+	assert.Empty(t, graph.MissingDependencies())
+	assert.Equal(t, map[AssetAddress][]AssetAddress{betaAddr: {btcPrice}}, graph.ExternalDependencies())
+}
+
+func TestFormulaBuilder(t *testing.T) {
+	t0 := NewTimeUnit(1587607201)
+	spot := UnitTimeArray{}
+	futures := UnitTimeArray{}
+	for i := 0; i < 20; i++ {
+		tu := t0.Add(time.Duration(i) * time.Second)
+		spot = append(spot, NewUnit(65000+float64(i)*10).ToTime(tu))
+		// futures miss a value, the formula is computed at the times shared by its dependencies
+		if i != 5 {
+			futures = append(futures, NewUnit(65100+float64(i)*12).ToTime(tu))
+		}
+	}
+
+	points, _, err := NewIndicatorDataBuilder(Asset.FORMULA, nil, []string{"($0.close - $1.close) / $1.close * 100"}, -1).ComputeBatchAll(futures, spot)
+	assert.Nil(t, err)
+	assert.Equal(t, 19, len(points))
+	for _, p := range points {
+		i := int(p.Time.ToTime().Sub(t0.ToTime()) / time.Second)
+		assert.InDelta(t, (futures[lo.Ternary(i > 5, i-1, i)].Close-spot[i].Close)/spot[i].Close*100, p.Value, 1e-9)
+	}
+
+	// operator precedence and unary minus
+	points, _, err = NewIndicatorDataBuilder(Asset.FORMULA, nil, []string{"$0.close * 0 - -2 * 3 + 4 / (1 + 1)"}, -1).ComputeBatch(spot)
+	assert.Nil(t, err)
+	assert.Equal(t, 8.0, points[0].Value)
+
+	// a division by zero computes no point
+	b := NewIndicatorDataBuilder(Asset.FORMULA, nil, []string{"$0.plus / ($0.plus + $0.minus)"}, -1)
+	p, err := b.ComputeUnsafe(QuantityTime{Quantity{Plus: 3, Minus: 1, PlusCount: 1, MinusCount: 1}, t0})
+	assert.Nil(t, err)
+	assert.Equal(t, 0.75, p.Value)
+	p, err = b.ComputeUnsafe(QuantityTime{Quantity{Plus: 0, Minus: 0, PlusCount: 1, MinusCount: 1}, t0.Add(time.Second)})
+	assert.Nil(t, err)
+	assert.Nil(t, p)
+
+	for _, expression := range []string{"", "$0.close +", "($0.close", "$0.close)", "$.close", "$0", "2 * 3", "$1.close", "$0.close $1.close", "$0.time"} {
+		_, _, err := NewIndicatorDataBuilder(Asset.FORMULA, nil, []string{expression}, -1).ComputeBatch(spot)
+		assert.NotNil(t, err, expression)
+	}
+
+	setID := []string{"btc", "usdt"}
+	spotAddr := AssetAddressParsed{SetID: setID, AssetType: Asset.SPOT_PRICE}.BuildAddress()
+	futuresAddr := AssetAddressParsed{SetID: setID, AssetType: Asset.FUTURES_PRICE}.BuildAddress()
+	volumeAddr := AssetAddressParsed{SetID: setID, AssetType: Asset.SPOT_VOLUME}.BuildAddress()
+	formula := AssetAddressParsed{SetID: setID, AssetType: Asset.FORMULA, Dependencies: []AssetAddress{futuresAddr, spotAddr}, Arguments: []string{"$0.close - $1.close"}}
+	assert.Nil(t, formula.IsValid())
+
+	formula.Dependencies = []AssetAddress{futuresAddr, spotAddr, volumeAddr}
+	assert.NotNil(t, formula.IsValid())
+	formula.Dependencies = []AssetAddress{futuresAddr}
+	assert.NotNil(t, formula.IsValid())
+	formula.Dependencies = []AssetAddress{volumeAddr, spotAddr}
+	assert.NotNil(t, formula.IsValid(), "a quantity has no close column")
+
+	// the reserved characters of addresses are escaped in arguments
+	formula = AssetAddressParsed{SetID: setID, AssetType: Asset.FORMULA, Dependencies: []AssetAddress{volumeAddr}, Arguments: []string{"$0.plus_average - $0.minus_average"}}
+	assert.Nil(t, formula.IsValid())
+	parsed, err := formula.BuildAddress().Parse()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"$0.plus_average-$0.minus_average"}, parsed.Arguments)
+	assert.Nil(t, parsed.IsValid())
+
+	notation, err := formula.Notation()
+	assert.Nil(t, err)
+	fromNotation, err := ParseAssetNotation(notation)
+	assert.Nil(t, err)
+	assert.Equal(t, formula.BuildAddress(), fromNotation.BuildAddress())
+}
+
+func TestAssetWarmup(t *testing.T) {
+	setID := []string{"btc", "usdt"}
+	price := AssetAddressParsed{SetID: setID, AssetType: Asset.SPOT_PRICE}
+	sma := AssetAddressParsed{SetID: setID, AssetType: Asset.SMA, Dependencies: []AssetAddress{price.BuildAddress()}, Arguments: []string{"close", "9"}}
+	rsi := AssetAddressParsed{SetID: setID, AssetType: Asset.RSI2, Dependencies: []AssetAddress{sma.BuildAddress()}, Arguments: []string{"value", "14"}}
+
+	warmup, err := price.WarmupPeriod()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, warmup)
+
+	// the warm-up matches the first value computed through the chain of indicators
+	smaBuilder := NewIndicatorDataBuilder(Asset.SMA, nil, sma.Arguments, -1)
+	rsiBuilder := NewIndicatorDataBuilder(Asset.RSI2, nil, rsi.Arguments, -1)
+	first := -1
+	for i, unit := range benchmarkUnits(100) {
+		p, err := smaBuilder.ComputeUnsafe(unit)
+		assert.Nil(t, err)
+		if p == nil {
+			continue
+		}
+		p, err = rsiBuilder.ComputeUnsafe(p.ToTime(unit.Time))
+		assert.Nil(t, err)
+		if p != nil {
+			first = i
+			break
+		}
+	}
+	warmup, err = rsi.WarmupPeriod()
+	assert.Nil(t, err)
+	assert.Equal(t, first+1, warmup)
+	assert.Equal(t, rsiBuilder.WarmupPeriod()+smaBuilder.WarmupPeriod()-1, warmup)
+
+	settings := AssetSettings{
+		Address:     AssetAddressParsedWithoutSetID{AssetType: rsi.AssetType, Dependencies: rsi.Dependencies, Arguments: rsi.Arguments},
+		MinDataDate: "2024-01-10",
+	}
+	start, err := settings.BackfillStart(setID, time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC).Add(-time.Duration(warmup-1)*time.Minute), start.ToTime())
+
+	rsi.Arguments = []string{"value", "0"}
+	_, err = rsi.WarmupPeriod()
+	assert.NotNil(t, err)
+}
+
+func TestIndicatorCheckpoints(t *testing.T) {
+	units := benchmarkUnits(200)
+	t0 := units[0].Time
+	retention := CheckpointRetention{Interval: 20 * time.Second, MaxCount: 4}
+
+	for _, assetType := range []AssetType{Asset.SMA, Asset.HMA, Asset.RSI2, Asset.PERCENTILE_RANK} {
+		args := []string{"close", "14"}
+		b := NewIndicatorDataBuilder(assetType, nil, args, -1)
+		b.SetCheckpointRetention(retention)
+		_, _, err := b.ComputeBatch(units)
+		assert.Nil(t, err)
+
+		checkpoints := b.Checkpoints()
+		assert.Equal(t, []TimeUnit{t0.Add(120 * time.Second), t0.Add(140 * time.Second), t0.Add(160 * time.Second), t0.Add(180 * time.Second)}, lo.Map(checkpoints, func(c IndicatorCheckpoint, _ int) TimeUnit {
+			return c.Time
+		}))
+
+		// a value arrives late: the indicator is replayed from the checkpoint before it
+		changed := append(UnitTimeArray{}, units...)
+		changed[170] = NewUnit(64000).ToTime(changed[170].Time)
+		expected, expectedState, err := NewIndicatorDataBuilder(assetType, nil, args, -1).ComputeBatch(changed)
+		assert.Nil(t, err)
+
+		replayed, state, err := b.ReplayFrom(changed[170].Time, changed)
+		assert.Nil(t, err)
+		assert.Equal(t, expected[len(expected)-len(replayed):], replayed, "%s replay should match a full computation", assetType)
+		assert.Equal(t, t0.Add(161*time.Second), replayed[0].Time)
+		assert.Equal(t, expectedState, state)
+		assert.Equal(t, lo.Map(checkpoints, func(c IndicatorCheckpoint, _ int) TimeUnit { return c.Time }), lo.Map(b.Checkpoints(), func(c IndicatorCheckpoint, _ int) TimeUnit { return c.Time }))
+
+		// checkpoints can be stored and loaded in another builder
+		other := NewIndicatorDataBuilder(assetType, nil, args, -1)
+		other.LoadCheckpoints(b.Checkpoints())
+		replayed, _, err = other.ReplayFrom(changed[150].Time, changed)
+		assert.Nil(t, err)
+		assert.Equal(t, expected[len(expected)-len(replayed):], replayed)
+
+		// the oldest checkpoints are not retained: the indicator is replayed from its initial state
+		from, err := b.RestoreBefore(t0.Add(120 * time.Second))
+		assert.Nil(t, err)
+		assert.Equal(t, TimeUnit(-1), from)
+		assert.Empty(t, b.Checkpoints())
+		replayed, state, err = b.ReplayFrom(t0.Add(120*time.Second), changed)
+		assert.Nil(t, err)
+		assert.Equal(t, expected, replayed)
+		assert.Equal(t, expectedState, state)
+	}
+
+	// checkpoints are recorded while streaming too
+	b := NewIndicatorDataBuilder(Asset.SMA, nil, []string{"close", "14"}, -1)
+	b.SetCheckpointRetention(CheckpointRetention{})
+	for _, unit := range units[:30] {
+		_, err := b.ComputeUnsafe(unit)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, 30, len(b.Checkpoints()))
+	from, err := b.RestoreBefore(units[20].Time)
+	assert.Nil(t, err)
+	assert.Equal(t, units[19].Time, from)
+	p, err := b.ComputeUnsafe(units[20])
+	assert.Nil(t, err)
+	expected, _, err := NewIndicatorDataBuilder(Asset.SMA, nil, []string{"close", "14"}, -1).ComputeBatch(units[:21])
+	assert.Nil(t, err)
+	assert.Equal(t, expected[len(expected)-1].Value, p.Value)
+
+	// vectors
+	macd := NewIndicatorDataBuilder(Asset.MACD, nil, []string{"close", "5", "12", "4"}, -1)
+	macd.SetCheckpointRetention(retention)
+	expectedVectors, _, err := macd.ComputeVectorBatch(units)
+	assert.Nil(t, err)
+	vectors, _, err := macd.ReplayVectorFrom(units[170].Time, units)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedVectors[len(expectedVectors)-len(vectors):], vectors)
+}
+
+func TestMultiTimeframeBuilder(t *testing.T) {
+	Env.MIN_TIME_FRAME = time.Second
+	t0 := NewTimeUnit(1587607200)
+	prices := UnitTimeArray{}
+	volumes := QuantityTimeArray{}
+	for i := 0; i < 600; i++ {
+		tu := t0.Add(time.Duration(i) * time.Second)
+		close := 65000 + float64(i%13)*3.5 - float64(i%7)*2.25
+		if i%41 != 7 {
+			prices = append(prices, Unit{Open: close - 1, High: close + 2, Low: close - 2, Close: close, Count: 3}.ToTime(tu))
+		}
+		volumes = append(volumes, QuantityTime{Quantity{Plus: float64(i%5) + 0.5, Minus: float64(i%3) + 0.25, PlusCount: 2, MinusCount: 1}, tu})
+	}
+
+	// a rolling indicator computed on the aggregated candles of each timeframe
+	mtfVWMA := registerTestAsset(t, AssetStateConfig{
+		SetUpDecimals:         DEFAULT_ASSETS[Asset.VWMA].SetUpDecimals,
+		ID:                    "test_mtf_vwma",
+		DataType:              POINT,
+		RequiredDependencies:  DEFAULT_ASSETS[Asset.VWMA].RequiredDependencies,
+		RequiredArguments:     DEFAULT_ASSETS[Asset.VWMA].RequiredArguments,
+		IndexedOnMinTimeframe: true,
+	}, getIndicatorFactory(Asset.VWMA))
+
+	args := []string{"close", "4"}
+	timeframes := []time.Duration{time.Second, 5 * time.Second, time.Minute}
+	b, err := NewMultiTimeframeBuilder(mtfVWMA, args, -1, timeframes, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, timeframes, b.Timeframes())
+
+	// the data of the minimum timeframe is received in chunks cutting candles
+	results := map[time.Duration]PointTimeArray{}
+	var states map[time.Duration][]byte
+	for _, cut := range [][2]int{{0, 157}, {157, 403}, {403, 600}} {
+		chunkPrices := lo.Filter(prices, func(u UnitTime, _ int) bool {
+			return u.Time >= t0.Add(time.Duration(cut[0])*time.Second) && u.Time < t0.Add(time.Duration(cut[1])*time.Second)
+		})
+		points, err := b.Compute(UnitTimeArray(chunkPrices), volumes[cut[0]:cut[1]])
+		assert.Nil(t, err)
+		for timeframe, p := range points {
+			results[timeframe] = append(results[timeframe], p...)
+		}
+		states = b.States()
+		if cut[0] == 0 {
+			assert.Equal(t, t0.Add(2*time.Minute), b.ResumeTime(), "the candle of the minute starting at 2:00 is incomplete")
+		}
+	}
+	assert.Equal(t, TimeUnit(-1), b.ResumeTime())
+
+	for _, timeframe := range timeframes {
+		// the data aggregated beforehand on the timeframe
+		aggregatedPrices := UnitTimeArray{}
+		aggregatedVolumes := QuantityTimeArray{}
+		for start := t0; start.Add(timeframe) <= t0.Add(600*time.Second); start = start.Add(timeframe) {
+			inCandle := func(tu TimeUnit) bool { return tu >= start && tu < start.Add(timeframe) }
+			candlePrices := lo.Filter(prices, func(u UnitTime, _ int) bool { return inCandle(u.Time) })
+			candleVolumes := lo.Filter(volumes, func(q QuantityTime, _ int) bool { return inCandle(q.Time) })
+			if timeframe == time.Second {
+				aggregatedPrices = append(aggregatedPrices, candlePrices...)
+				aggregatedVolumes = append(aggregatedVolumes, candleVolumes...)
+				continue
+			}
+			aggregatedPrices = append(aggregatedPrices, UnitTimeArray(candlePrices).Aggregate(timeframe, start).(UnitTime))
+			aggregatedVolumes = append(aggregatedVolumes, QuantityTimeArray(candleVolumes).Aggregate(timeframe, start).(QuantityTime))
+		}
+		expected, expectedState, err := NewIndicatorDataBuilder(mtfVWMA, nil, args, -1).ComputeBatchAll(aggregatedPrices, aggregatedVolumes)
+		assert.Nil(t, err)
+		assert.NotEmpty(t, expected)
+		assert.Equal(t, expected, results[timeframe], "timeframe %s", timeframe)
+		assert.Equal(t, expectedState, states[timeframe], "timeframe %s", timeframe)
+	}
+
+	_, err = NewMultiTimeframeBuilder(Asset.SMA, []string{"close", "4"}, -1, timeframes, nil)
+	assert.NotNil(t, err, "SMA is not indexed on the minimum timeframe")
+	_, err = NewMultiTimeframeBuilder(mtfVWMA, args, -1, []time.Duration{1500 * time.Millisecond}, nil)
+	assert.NotNil(t, err)
+
+	// indicators of candles receive the values of the minimum timeframe instead of the aggregated candles
+	rowCount := registerTestAsset(t, AssetStateConfig{
+		SetUpDecimals:         DEFAULT_ASSETS[Asset.VWMA].SetUpDecimals,
+		ID:                    "test_mtf_row_count",
+		DataType:              POINT,
+		RequiredDependencies:  []DependencyConstraint{{DataTypes: []DataType{UNIT}}, {DataTypes: []DataType{QUANTITY}}},
+		IndexedOnMinTimeframe: true,
+	}, func() Indicator { return &rowCountIndicator{} })
+	b, err = NewMultiTimeframeBuilder(rowCount, nil, -1, []time.Duration{time.Minute}, nil)
+	assert.Nil(t, err)
+	points, err := b.Compute(prices, volumes)
+	assert.Nil(t, err)
+	assert.Equal(t, 10, len(points[time.Minute]))
+	for _, p := range points[time.Minute] {
+		rows := lo.CountBy(prices, func(u UnitTime) bool { return u.Time >= p.Time && u.Time < p.Time.Add(time.Minute) })
+		assert.Equal(t, float64(rows), p.Value, "candle at %s", p.Time.Pretty())
+	}
+}
+
+// rowCountIndicator outputs the number of values of the minimum timeframe of a candle.
+type rowCountIndicator struct {
+	statelessIndicator
+}
+
+func (ind *rowCountIndicator) Init(args IndicatorArguments) error { return nil }
+func (ind *rowCountIndicator) UpdateCandle(rows [][]Data) (Point, bool) {
+	return Point{float64(len(rows))}, true
+}
+func (ind *rowCountIndicator) UpdateAll(dataList []Data) (Point, bool) {
+	return ind.UpdateCandle([][]Data{dataList})
+}
+
+// registerTestAsset registers the asset if it is not registered yet and returns its type.
+func registerTestAsset(t *testing.T, config AssetStateConfig, indicator IndicatorFactory) AssetType {
+	if _, ok := GetAssetConfig(config.ID); !ok {
+		assert.Nil(t, RegisterAsset(config, indicator))
+	}
+	return config.ID
+}
+
+func TestMultiTimeframeVWAP(t *testing.T) {
+	Env.MIN_TIME_FRAME = time.Second
+	t0 := NewTimeUnit(1587607200)
+	prices := UnitTimeArray{}
+	volumes := QuantityTimeArray{}
+	for i := 0; i < 600; i++ {
+		tu := t0.Add(time.Duration(i) * time.Second)
+		close := 65000 + float64(i%13)*3.5 - float64(i%7)*2.25
+		if i%41 != 7 {
+			prices = append(prices, Unit{Open: close - 1, High: close + 2, Low: close - 2, Close: close, Count: 3}.ToTime(tu))
+		}
+		volumes = append(volumes, QuantityTime{Quantity{Plus: float64(i%5) + 0.5, Minus: float64(i%3) + 0.25, PlusCount: 2, MinusCount: 1}, tu})
+	}
+
+	timeframes := []time.Duration{time.Second, 5 * time.Second, time.Minute}
+	b, err := NewMultiTimeframeBuilder(Asset.WA, []string{"close"}, -1, timeframes, nil)
+	assert.Nil(t, err)
+	results, err := b.Compute(prices, volumes)
+	assert.Nil(t, err)
+	states := b.States()
+
+	for _, timeframe := range timeframes {
+		// the VWAP of each candle of the timeframe, from the data of the minimum timeframe
+		expected := PointTimeArray{}
+		for start := t0; start.Add(timeframe) <= t0.Add(600*time.Second); start = start.Add(timeframe) {
+			sumPriceVolume, sumVolume := 0.0, 0.0
+			for _, u := range prices {
+				if u.Time < start || u.Time >= start.Add(timeframe) {
+					continue
+				}
+				q, ok := lo.Find(volumes, func(q QuantityTime) bool { return q.Time == u.Time })
+				assert.True(t, ok)
+				sumPriceVolume += u.Close * (q.Plus + q.Minus)
+				sumVolume += q.Plus + q.Minus
+			}
+			if sumVolume > 0 {
+				expected = append(expected, Point{sumPriceVolume / sumVolume}.ToTime(start))
+			}
+		}
+		assert.NotEmpty(t, expected)
+		assert.Equal(t, len(expected), len(results[timeframe]), "timeframe %s", timeframe)
+		for i := range expected {
+			assert.Equal(t, expected[i].Time, results[timeframe][i].Time)
+			assert.InDelta(t, expected[i].Value, results[timeframe][i].Value, 1e-9, "timeframe %s", timeframe)
+		}
+		assert.Nil(t, states[timeframe], "the VWAP of a candle does not depend on the previous ones")
+	}
+
+	// WA is not a moving average
+	aggregatedPrices := UnitTimeArray{}
+	aggregatedVolumes := QuantityTimeArray{}
+	for start := t0; start < t0.Add(600*time.Second); start = start.Add(time.Minute) {
+		inCandle := func(tu TimeUnit) bool { return tu >= start && tu < start.Add(time.Minute) }
+		aggregatedPrices = append(aggregatedPrices, UnitTimeArray(lo.Filter(prices, func(u UnitTime, _ int) bool { return inCandle(u.Time) })).Aggregate(time.Minute, start).(UnitTime))
+		aggregatedVolumes = append(aggregatedVolumes, QuantityTimeArray(lo.Filter(volumes, func(q QuantityTime, _ int) bool { return inCandle(q.Time) })).Aggregate(time.Minute, start).(QuantityTime))
+	}
+	vwma, _, err := NewIndicatorDataBuilder(Asset.VWMA, nil, []string{"close", "1"}, -1).ComputeBatchAll(aggregatedPrices, aggregatedVolumes)
+	assert.Nil(t, err)
+	assert.NotEqual(t, vwma, results[time.Minute], "the VWAP weights the prices of the minimum timeframe, not the close of the candle")
+}
+
+func TestBookDepthIndicators(t *testing.T) {
+	t0 := NewTimeUnit(1587607200)
+	bids := make([]UnitTimeArray, 5)
+	asks := make([]UnitTimeArray, 5)
+	for i := 0; i < 40; i++ {
+		tu := t0.Add(time.Duration(i) * time.Minute)
+		for band := 0; band < 5; band++ {
+			bids[band] = append(bids[band], NewUnit(float64(band+1)*1000+float64(i%7)*35+float64(band*band)*12).ToTime(tu))
+			asks[band] = append(asks[band], NewUnit(float64(band+1)*900+float64(i%5)*41).ToTime(tu))
+		}
+	}
+	compute := func(assetType AssetType, args []string, lists ...DataList) []float64 {
+		points, _, err := NewIndicatorDataBuilder(assetType, nil, args, -1).ComputeBatchAll(lists...)
+		assert.Nil(t, err)
+		return lo.Map(points, func(p PointTime, _ int) float64 { return p.Value })
+	}
+
+	imbalance := compute(Asset.BOOK_IMBALANCE, []string{"close"}, bids[1], asks[1])
+	cumulative := compute(Asset.BOOK_CUMULATIVE_DEPTH, []string{"close"}, bids[1], asks[1])
+	slope := compute(Asset.BOOK_SLOPE, []string{"close"}, bids[0], bids[1], bids[2], bids[3], bids[4])
+	change := compute(Asset.BOOK_DEPTH_CHANGE, []string{"close", "10"}, asks[0])
+	assert.Equal(t, 30, len(change))
+
+	for i := range bids[0] {
+		bid, ask := bids[1][i].Close, asks[1][i].Close
+		assert.InDelta(t, (bid-ask)/(bid+ask), imbalance[i], 1e-12)
+		assert.InDelta(t, bid+ask, cumulative[i], 1e-9)
+
+		x := []float64{1, 2, 3, 4, 5}
+		y := lo.Map(bids, func(side UnitTimeArray, _ int) float64 { return side[i].Close })
+		covariance, variance := 0.0, 0.0
+		for j := range x {
+			covariance += (x[j] - 3) * (y[j] - lo.Sum(y)/5)
+			variance += (x[j] - 3) * (x[j] - 3)
+		}
+		assert.InDelta(t, covariance/variance, slope[i], 1e-9)
+
+		if i >= 10 {
+			assert.InDelta(t, 100*(asks[0][i].Close-asks[0][i-10].Close)/asks[0][i-10].Close, change[i-10], 1e-9)
+		}
+	}
+
+	setID := []string{"btc", "usdt"}
+	address := func(assetType AssetType) AssetAddress {
+		return AssetAddressParsed{SetID: setID, AssetType: assetType}.BuildAddress()
+	}
+	band := AssetAddressParsed{SetID: setID, AssetType: Asset.BOOK_IMBALANCE, Dependencies: []AssetAddress{address(Asset.BOOK_DEPTH_M2), address(Asset.BOOK_DEPTH_P2)}, Arguments: []string{"close"}}
+	assert.Nil(t, band.IsValid())
+	band.Dependencies = []AssetAddress{address(Asset.BOOK_DEPTH_M1), address(Asset.BOOK_DEPTH_P2)}
+	assert.NotNil(t, band.IsValid(), "the sides of different bands cannot be compared")
+	band.Dependencies = []AssetAddress{address(Asset.BOOK_DEPTH_P2), address(Asset.BOOK_DEPTH_M2)}
+	assert.NotNil(t, band.IsValid(), "the bid side comes first")
+	band.Dependencies = []AssetAddress{address(Asset.SPOT_PRICE), address(Asset.BOOK_DEPTH_P2)}
+	assert.NotNil(t, band.IsValid())
+
+	bookDepth := func(percentage string) AssetAddress {
+		return AssetAddress("btc_usdt;book_depth;[];" + percentage)
+	}
+	band.Dependencies = []AssetAddress{bookDepth("-0.5"), bookDepth("0.5")}
+	assert.Nil(t, band.IsValid(), "any percentage can be compared")
+	band.Dependencies = []AssetAddress{bookDepth("-2"), address(Asset.BOOK_DEPTH_P2)}
+	assert.Nil(t, band.IsValid(), "the parametric and legacy assets can be compared")
+
+	side := AssetAddressParsed{SetID: setID, AssetType: Asset.BOOK_SLOPE, Dependencies: []AssetAddress{
+		address(Asset.BOOK_DEPTH_P1), address(Asset.BOOK_DEPTH_P2), address(Asset.BOOK_DEPTH_P3), address(Asset.BOOK_DEPTH_P4), address(Asset.BOOK_DEPTH_P5),
+	}, Arguments: []string{"close"}}
+	assert.Nil(t, side.IsValid())
+	side.Dependencies[2] = address(Asset.BOOK_DEPTH_M3)
+	assert.NotNil(t, side.IsValid(), "the bands must be on the same side")
+	side.Dependencies[2] = address(Asset.BOOK_DEPTH_P4)
+	assert.NotNil(t, side.IsValid(), "the bands are sorted")
+}
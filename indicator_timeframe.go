@@ -0,0 +1,211 @@
+package pcommon
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/samber/lo"
+)
+
+/*
+	Assets indexed on the minimum timeframe (see AssetStateConfig.IndexedOnMinTimeframe) are not computed from the data
+	of their dependencies on each timeframe, but from the data of the minimum timeframe aggregated into the candles of each timeframe.
+	Indicators computing a candle from the values of the minimum timeframe it contains instead (ex: a VWAP, see MinTimeframeIndicator)
+	receive these values, time-aligned, rather than the aggregated candles.
+
+	A candle of a timeframe starts at a multiple of the timeframe and is computed once complete: when data after its end
+	is received or when the last value of the minimum timeframe it contains is received.
+	The data of the incomplete candle is kept until the next call.
+*/
+
+// MultiTimeframeBuilder computes an asset indexed on the minimum timeframe on several timeframes, with one indicator state per timeframe.
+type MultiTimeframeBuilder struct {
+	builders map[time.Duration]*IndicatorDataBuilder
+	// data of the minimum timeframe of the incomplete candle of each timeframe, one list per dependency
+	pending map[time.Duration][][]Data
+}
+
+// NewMultiTimeframeBuilder returns a builder of the asset on the timeframes, each multiple of Env.MIN_TIME_FRAME.
+// states are the previous states of the indicator on each timeframe, they can be nil.
+func NewMultiTimeframeBuilder(assetType AssetType, arguments []string, precision int8, timeframes []time.Duration, states map[time.Duration][]byte) (*MultiTimeframeBuilder, error) {
+	config, ok := GetAssetConfig(assetType)
+	if !ok {
+		return nil, fmt.Errorf("asset type %s is not registered", assetType)
+	}
+	if !config.IndexedOnMinTimeframe {
+		return nil, fmt.Errorf("asset type %s is not indexed on the minimum timeframe", assetType)
+	}
+	if config.DataType != POINT {
+		return nil, fmt.Errorf("asset type %s does not compute points", assetType)
+	}
+
+	b := &MultiTimeframeBuilder{
+		builders: make(map[time.Duration]*IndicatorDataBuilder),
+		pending:  make(map[time.Duration][][]Data),
+	}
+	for _, timeframe := range timeframes {
+		if timeframe < Env.MIN_TIME_FRAME || timeframe%Env.MIN_TIME_FRAME != 0 {
+			return nil, fmt.Errorf("timeframe %s is not a multiple of the minimum timeframe %s", timeframe, Env.MIN_TIME_FRAME)
+		}
+		if _, ok := b.builders[timeframe]; ok {
+			return nil, fmt.Errorf("timeframe %s is duplicated", timeframe)
+		}
+		builder := NewIndicatorDataBuilder(assetType, states[timeframe], arguments, precision)
+		if builder.initErr != nil {
+			return nil, builder.initErr
+		}
+		b.builders[timeframe] = builder
+	}
+	return b, nil
+}
+
+// Timeframes returns the timeframes of the builder in ascending order.
+func (b *MultiTimeframeBuilder) Timeframes() []time.Duration {
+	ret := lo.Keys(b.builders)
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// Builder returns the builder of the indicator on the timeframe, nil if the timeframe is not computed.
+func (b *MultiTimeframeBuilder) Builder(timeframe time.Duration) *IndicatorDataBuilder {
+	return b.builders[timeframe]
+}
+
+// States returns the state of the indicator on each timeframe.
+func (b *MultiTimeframeBuilder) States() map[time.Duration][]byte {
+	ret := make(map[time.Duration][]byte, len(b.builders))
+	for timeframe, builder := range b.builders {
+		ret[timeframe] = builder.PrevState()
+	}
+	return ret
+}
+
+// ResumeTime returns the start of the oldest incomplete candle, -1 if there is none.
+// Once the builder is created again from its states, the data of the minimum timeframe must be provided from this time.
+func (b *MultiTimeframeBuilder) ResumeTime() TimeUnit {
+	ret := TimeUnit(-1)
+	for timeframe, pending := range b.pending {
+		for _, list := range pending {
+			if len(list) > 0 {
+				start := timeframeStart(list[0].GetTime(), timeframe)
+				if ret == -1 || start < ret {
+					ret = start
+				}
+			}
+		}
+	}
+	return ret
+}
+
+// Compute aggregates the data of the minimum timeframe of each dependency (one list per dependency, sorted by time)
+// into the candles of each timeframe and computes the indicator on the complete ones.
+func (b *MultiTimeframeBuilder) Compute(lists ...DataList) (map[time.Duration]PointTimeArray, error) {
+	ret := make(map[time.Duration]PointTimeArray, len(b.builders))
+	for timeframe, builder := range b.builders {
+		points, err := b.computeTimeframe(timeframe, builder, lists)
+		if err != nil {
+			return nil, fmt.Errorf("timeframe %s: %s", timeframe, err)
+		}
+		ret[timeframe] = points
+	}
+	return ret, nil
+}
+
+func (b *MultiTimeframeBuilder) computeTimeframe(timeframe time.Duration, builder *IndicatorDataBuilder, lists []DataList) (PointTimeArray, error) {
+	pending := b.pending[timeframe]
+	if pending == nil {
+		pending = make([][]Data, len(lists))
+	}
+	if len(pending) != len(lists) {
+		return nil, fmt.Errorf("expected %d dependency lists, got %d", len(pending), len(lists))
+	}
+
+	// values of each dependency grouped by candle
+	candles := make(map[TimeUnit][][]Data)
+	last := TimeUnit(-1)
+	for i, list := range lists {
+		for _, data := range append(pending[i], list.Map()...) {
+			start := timeframeStart(data.GetTime(), timeframe)
+			if _, ok := candles[start]; !ok {
+				candles[start] = make([][]Data, len(lists))
+			}
+			candles[start][i] = append(candles[start][i], data)
+			if data.GetTime() > last {
+				last = data.GetTime()
+			}
+		}
+	}
+
+	starts := lo.Keys(candles)
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	ret := PointTimeArray{}
+	b.pending[timeframe] = make([][]Data, len(lists))
+	for _, start := range starts {
+		// the last value of the minimum timeframe of the candle completes it
+		if last < start.Add(timeframe-Env.MIN_TIME_FRAME) {
+			b.pending[timeframe] = candles[start]
+			break
+		}
+
+		p, err := computeCandle(builder, timeframe, start, candles[start])
+		if err != nil {
+			return nil, err
+		}
+		if p != nil {
+			ret = append(ret, p.ToTime(start))
+		}
+	}
+	return ret, nil
+}
+
+// computeCandle computes the indicator on the candle of the timeframe starting at start,
+// from the values of the minimum timeframe of each dependency it contains.
+// A candle is computed only if every dependency has a value in it, like in AlignDataLists.
+func computeCandle(builder *IndicatorDataBuilder, timeframe time.Duration, start TimeUnit, values [][]Data) (*Point, error) {
+	if _, ok := builder.indicator.(MinTimeframeIndicator); ok {
+		rows := alignData(values)
+		if len(rows) == 0 {
+			return nil, nil
+		}
+		return builder.ComputeCandleUnsafe(start, rows)
+	}
+
+	if lo.SomeBy(values, func(v []Data) bool { return len(v) == 0 }) {
+		return nil, nil
+	}
+	dataList := make([]Data, len(values))
+	for i, v := range values {
+		aggregated, err := aggregateCandle(v, timeframe, start)
+		if err != nil {
+			return nil, err
+		}
+		dataList[i] = aggregated
+	}
+	return builder.ComputeUnsafe(dataList...)
+}
+
+// aggregateCandle returns the candle of the timeframe starting at start from the values of the minimum timeframe it contains.
+func aggregateCandle(values []Data, timeframe time.Duration, start TimeUnit) (Data, error) {
+	// values of the minimum timeframe are candles already
+	if timeframe == Env.MIN_TIME_FRAME {
+		return values[0], nil
+	}
+
+	dataType := values[0].Type()
+	if dataType != UNIT && dataType != QUANTITY {
+		return nil, fmt.Errorf("%s data cannot be aggregated", dataType.String())
+	}
+	list := NewTypeTimeArray(dataType)
+	for _, v := range values {
+		list = list.Append(v)
+	}
+	return list.Aggregate(timeframe, start), nil
+}
+
+// timeframeStart returns the start of the candle of the timeframe containing t.
+func timeframeStart(t TimeUnit, timeframe time.Duration) TimeUnit {
+	size := TimeUnit(timeframe / TIME_UNIT_DURATION)
+	return t - t%size
+}
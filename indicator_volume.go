@@ -0,0 +1,280 @@
+package pcommon
+
+/*
+	Indicators combining the candle of a UNIT price and the volume of a QUANTITY of the same set.
+	With the "taker" method, the direction of the volume is read from the taker side (Plus is bought, Minus is sold)
+	instead of being guessed from the move of the price.
+*/
+
+const volumeMethodClose = "close"
+const volumeMethodTaker = "taker"
+
+// priceVolumeDependencies are a price candle and a volume at the same time.
+func priceVolumeDependencies() []DependencyConstraint {
+	return []DependencyConstraint{
+		candleDependency(),
+		{DataTypes: []DataType{QUANTITY}},
+	}
+}
+
+func volumeMethodArgument() ArgumentSchema {
+	return ArgumentSchema{
+		Name:          "method",
+		Type:          ARG_STRING,
+		Default:       volumeMethodClose,
+		AllowedValues: []string{volumeMethodClose, volumeMethodTaker},
+		Description:   "How the direction of the volume is found: from the move of the price (close) or from the taker side of the trades (taker).",
+	}
+}
+
+// candleVolumeOf returns the candle and the quantity of the dependencies, ok is false if one of them is missing or empty.
+func candleVolumeOf(dataList []Data) (Unit, Quantity, bool) {
+	u, ok := candleOf(dataList[0])
+	if !ok {
+		return Unit{}, Quantity{}, false
+	}
+	q, ok := quantityOf(dataList[1])
+	if !ok {
+		return Unit{}, Quantity{}, false
+	}
+	return u, q, true
+}
+
+// closeLocationValue is the position of the close in the range of the candle, from -1 (low) to 1 (high).
+func closeLocationValue(u Unit) float64 {
+	if u.High == u.Low {
+		return 0
+	}
+	return ((u.Close - u.Low) - (u.High - u.Close)) / (u.High - u.Low)
+}
+
+/* ON-BALANCE VOLUME : OBV */
+type obvState struct {
+	OBV       float64
+	PrevClose float64
+	HasPrev   bool
+}
+
+type obvIndicator struct {
+	method string
+	state  obvState
+}
+
+func (ind *obvIndicator) Init(args IndicatorArguments) error {
+	ind.method = args.String("method")
+	return nil
+}
+
+func (ind *obvIndicator) UpdateAll(dataList []Data) (Point, bool) {
+	u, q, ok := candleVolumeOf(dataList)
+	if !ok {
+		return Point{}, false
+	}
+
+	if ind.method == volumeMethodTaker {
+		ind.state.OBV += q.Plus - q.Minus
+		return Point{ind.state.OBV}, true
+	}
+
+	defer func() {
+		ind.state.PrevClose = u.Close
+		ind.state.HasPrev = true
+	}()
+	if !ind.state.HasPrev {
+		return Point{}, false
+	}
+	if u.Close > ind.state.PrevClose {
+		ind.state.OBV += q.Plus + q.Minus
+	} else if u.Close < ind.state.PrevClose {
+		ind.state.OBV -= q.Plus + q.Minus
+	}
+	return Point{ind.state.OBV}, true
+}
+
+func (ind *obvIndicator) WarmupPeriod() int {
+	if ind.method == volumeMethodTaker {
+		return 1
+	}
+	return 2
+}
+
+func (ind *obvIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	e.Float(ind.state.OBV)
+	e.Float(ind.state.PrevClose)
+	e.Bool(ind.state.HasPrev)
+	return e.Bytes(), nil
+}
+
+func (ind *obvIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.OBV = d.Float()
+	ind.state.PrevClose = d.Float()
+	ind.state.HasPrev = d.Bool()
+	return d.Err()
+}
+
+/* ACCUMULATION/DISTRIBUTION LINE : AD */
+type adIndicator struct {
+	ad float64
+}
+
+func (ind *adIndicator) Init(args IndicatorArguments) error {
+	return nil
+}
+
+func (ind *adIndicator) UpdateAll(dataList []Data) (Point, bool) {
+	u, q, ok := candleVolumeOf(dataList)
+	if !ok {
+		return Point{}, false
+	}
+	ind.ad += closeLocationValue(u) * (q.Plus + q.Minus)
+	return Point{ind.ad}, true
+}
+
+func (ind *adIndicator) WarmupPeriod() int {
+	return 1
+}
+
+func (ind *adIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	e.Float(ind.ad)
+	return e.Bytes(), nil
+}
+
+func (ind *adIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.ad = d.Float()
+	return d.Err()
+}
+
+/* MONEY FLOW INDEX : MFI */
+type mfiState struct {
+	Positive    rollingSum
+	Negative    rollingSum
+	PrevTypical float64
+	HasPrev     bool
+}
+
+type mfiIndicator struct {
+	period int
+	method string
+	state  mfiState
+}
+
+func (ind *mfiIndicator) Init(args IndicatorArguments) error {
+	ind.period = int(args.Int("period"))
+	ind.method = args.String("method")
+	ind.state = mfiState{Positive: newRollingSum(ind.period), Negative: newRollingSum(ind.period)}
+	return nil
+}
+
+func (ind *mfiIndicator) UpdateAll(dataList []Data) (Point, bool) {
+	u, q, ok := candleVolumeOf(dataList)
+	if !ok {
+		return Point{}, false
+	}
+	typical := (u.High + u.Low + u.Close) / 3
+
+	var positive, negative float64
+	if ind.method == volumeMethodTaker {
+		positive, negative = typical*q.Plus, typical*q.Minus
+	} else {
+		defer func() {
+			ind.state.PrevTypical = typical
+			ind.state.HasPrev = true
+		}()
+		if !ind.state.HasPrev {
+			return Point{}, false
+		}
+		if typical > ind.state.PrevTypical {
+			positive = typical * (q.Plus + q.Minus)
+		} else if typical < ind.state.PrevTypical {
+			negative = typical * (q.Plus + q.Minus)
+		}
+	}
+
+	sumPositive := ind.state.Positive.Push(positive)
+	sumNegative := ind.state.Negative.Push(negative)
+	if !ind.state.Positive.Full() {
+		return Point{}, false
+	}
+	if sumPositive+sumNegative == 0 {
+		return Point{50}, true
+	}
+	return Point{100 * sumPositive / (sumPositive + sumNegative)}, true
+}
+
+func (ind *mfiIndicator) WarmupPeriod() int {
+	if ind.method == volumeMethodTaker {
+		return ind.period
+	}
+	return ind.period + 1
+}
+
+func (ind *mfiIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.Positive.encode(e)
+	ind.state.Negative.encode(e)
+	e.Float(ind.state.PrevTypical)
+	e.Bool(ind.state.HasPrev)
+	return e.Bytes(), nil
+}
+
+func (ind *mfiIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.Positive.decode(d)
+	ind.state.Negative.decode(d)
+	ind.state.PrevTypical = d.Float()
+	ind.state.HasPrev = d.Bool()
+	return d.Err()
+}
+
+/* CHAIKIN MONEY FLOW : CMF */
+type cmfState struct {
+	MoneyFlowVolume rollingSum
+	Volume          rollingSum
+}
+
+type cmfIndicator struct {
+	period int
+	state  cmfState
+}
+
+func (ind *cmfIndicator) Init(args IndicatorArguments) error {
+	ind.period = int(args.Int("period"))
+	ind.state = cmfState{MoneyFlowVolume: newRollingSum(ind.period), Volume: newRollingSum(ind.period)}
+	return nil
+}
+
+func (ind *cmfIndicator) UpdateAll(dataList []Data) (Point, bool) {
+	u, q, ok := candleVolumeOf(dataList)
+	if !ok {
+		return Point{}, false
+	}
+	volume := q.Plus + q.Minus
+	sumMoneyFlowVolume := ind.state.MoneyFlowVolume.Push(closeLocationValue(u) * volume)
+	sumVolume := ind.state.Volume.Push(volume)
+	if !ind.state.Volume.Full() || sumVolume <= 0 {
+		return Point{}, false
+	}
+	return Point{sumMoneyFlowVolume / sumVolume}, true
+}
+
+func (ind *cmfIndicator) WarmupPeriod() int {
+	return ind.period
+}
+
+func (ind *cmfIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.MoneyFlowVolume.encode(e)
+	ind.state.Volume.encode(e)
+	return e.Bytes(), nil
+}
+
+func (ind *cmfIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.MoneyFlowVolume.decode(d)
+	ind.state.Volume.decode(d)
+	return d.Err()
+}
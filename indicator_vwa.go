@@ -1,32 +1,129 @@
 package pcommon
 
-import "errors"
+/*
+	VOLUME WEIGHTED AVERAGE PRICE : WA
+	VOLUME WEIGHTED MOVING AVERAGE : VWMA
 
-type emptyState struct{}
+	WA is the VWAP of each candle, computed from the prices and volumes of the minimum timeframe the candle contains
+	(see MultiTimeframeBuilder). VWMA is a moving average over a rolling window of candles of a timeframe.
+*/
+
+// Compacted holds values by time, ex: the price and the volume of a time.
+//
+// Deprecated: WA is computed by MultiTimeframeBuilder from the data of the minimum timeframe of its dependencies.
 type Compacted map[TimeUnit][]float64
 
-func (state *emptyState) buildVWA(c Compacted) (*Point, error) {
-	var totalVolume float64
-	var vwapNumerator float64
+// quantityOf returns the quantity of data, ok is false if data is not a quantity or is empty.
+func quantityOf(data Data) (Quantity, bool) {
+	var q Quantity
+	switch v := data.(type) {
+	case QuantityTime:
+		q = v.Quantity
+	case *QuantityTime:
+		q = v.Quantity
+	default:
+		return Quantity{}, false
+	}
+	return q, !q.IsEmpty()
+}
+
+// vwaState keeps the sums of price × volume and of volume over the window.
+type vwaState struct {
+	PriceVolume rollingSum
+	Volume      rollingSum
+}
+
+func newVWAState(period int) vwaState {
+	return vwaState{PriceVolume: newRollingSum(period), Volume: newRollingSum(period)}
+}
 
-	if len(c) == 0 {
-		return &Point{Value: 0.0}, nil
+func (state *vwaState) build(price, volume float64) (Point, bool) {
+	sumPriceVolume := state.PriceVolume.Push(price * volume)
+	sumVolume := state.Volume.Push(volume)
+	if !state.Volume.Full() || sumVolume <= 0 {
+		return Point{}, false
 	}
+	return Point{sumPriceVolume / sumVolume}, true
+}
 
-	for _, data := range c {
-		if len(data) != 2 {
-			return nil, errors.New("Invalid data format")
-		}
-		first := data[0]
-		second := data[1]
+func (state *vwaState) encode(e *stateEncoder) {
+	state.PriceVolume.encode(e)
+	state.Volume.encode(e)
+}
+
+func (state *vwaState) decode(d *stateDecoder) {
+	state.PriceVolume.decode(d)
+	state.Volume.decode(d)
+}
+
+// vwapIndicator computes the VWAP of a candle: a column of the units of the minimum timeframe it contains,
+// weighted by the total volume (plus and minus) of the quantity at the same time.
+type vwapIndicator struct {
+	statelessIndicator
+	column ColumnName
+}
+
+func (ind *vwapIndicator) Init(args IndicatorArguments) error {
+	ind.column = args.Column("column")
+	return nil
+}
 
-		vwapNumerator += first * second
-		totalVolume += second
+// UpdateCandle returns the VWAP of the candle, the candles without volume have none.
+func (ind *vwapIndicator) UpdateCandle(rows [][]Data) (Point, bool) {
+	sumPriceVolume, sumVolume := 0.0, 0.0
+	for _, row := range rows {
+		q, ok := quantityOf(row[1])
+		if !ok || row[0].IsEmpty() {
+			continue
+		}
+		volume := q.Plus + q.Minus
+		sumPriceVolume += columnValue(row[0], ind.column) * volume
+		sumVolume += volume
 	}
+	if sumVolume <= 0 {
+		return Point{}, false
+	}
+	return Point{sumPriceVolume / sumVolume}, true
+}
+
+// UpdateAll returns the VWAP of a candle of the minimum timeframe.
+func (ind *vwapIndicator) UpdateAll(dataList []Data) (Point, bool) {
+	return ind.UpdateCandle([][]Data{dataList})
+}
+
+// vwmaIndicator weights a column of its first dependency by the total volume (plus and minus) of a quantity at the same time,
+// over a rolling window.
+type vwmaIndicator struct {
+	maIndicator
+	state vwaState
+}
 
-	if totalVolume == 0 {
-		return &Point{Value: 0.0}, nil
+func (ind *vwmaIndicator) Init(args IndicatorArguments) error {
+	ind.maIndicator.Init(args)
+	ind.state = newVWAState(ind.period)
+	return nil
+}
+
+func (ind *vwmaIndicator) UpdateAll(dataList []Data) (Point, bool) {
+	q, ok := quantityOf(dataList[1])
+	if !ok {
+		return Point{}, false
 	}
+	return ind.state.build(columnValue(dataList[0], ind.column), q.Plus+q.Minus)
+}
+
+func (ind *vwmaIndicator) WarmupPeriod() int {
+	return ind.period
+}
+
+func (ind *vwmaIndicator) MarshalState() ([]byte, error) {
+	e := newStateEncoder()
+	ind.state.encode(e)
+	return e.Bytes(), nil
+}
 
-	return &Point{Value: vwapNumerator / totalVolume}, nil
+func (ind *vwmaIndicator) UnmarshalState(state []byte) error {
+	d := newStateDecoder(state)
+	ind.state.decode(d)
+	return d.Err()
 }
@@ -10,6 +10,6 @@ func BuildRessources() RessourcesJSON {
 	for s := range SET_ARCHIVES {
 		res.AvailableSetTypes = append(res.AvailableSetTypes, s.JSON())
 	}
-	res.AvailableAssets = DEFAULT_ASSETS.JSON()
+	res.AvailableAssets = RegisteredAssets().JSON()
 	return res
 }
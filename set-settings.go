@@ -23,6 +23,9 @@ func (s SetSettings) DBPath() string {
 }
 
 func (s SetSettings) ContainsAssetAddress(address AssetAddress) bool {
+	if canonical, err := address.Canonical(); err == nil {
+		address = canonical
+	}
 	for _, asset := range s.Assets {
 		if asset.Address.AddSetID(s.ID).BuildAddress() == address {
 			return true
@@ -103,7 +106,7 @@ func (s *SetSettings) IsBinancePair() error {
 		for _, asset := range s.Assets {
 
 			assetAddress := asset.Address.AddSetID(s.ID)
-			if !assetAddress.HasArguments() && !assetAddress.HasDependencies() {
+			if !assetAddress.HasDependencies() {
 				if lo.IndexOf(supportedAsset, assetAddress.AssetType) == -1 {
 					return fmt.Errorf("unsupported asset")
 				}